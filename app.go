@@ -28,12 +28,28 @@ var examples = []string{
 	"kubectl-slice -f foo.yaml --exclude-name *-svc --stdout",
 	"kubectl-slice -f foo.yaml --include Pod/* --stdout",
 	"kubectl-slice -f foo.yaml --exclude deployment/kube* --stdout",
+	"kubectl-slice -f foo.yaml -o ./ --include-namespace kube-*",
+	"kubectl-slice -f foo.yaml -o ./ -l app=web,tier!=cache",
+	"kubectl-slice -f foo.yaml -o ./ --exclude-annotation helm.sh/hook=*",
+	"kubectl-slice -f foo.yaml -o ./ --where 'spec.replicas > 1'",
+	"kubectl-slice -f foo.yaml -o ./ --include-regex '^deployment/(api|worker)-v[0-9]+$'",
+	"kubectl-slice -f foo.yaml -o ./ --include-group *.k8s.io --exclude-api-version *beta*",
+	"kubectl-slice -f foo.yaml --exclude-kind Secret --dry-run --explain",
+	"kubectl-slice -f foo.yaml -o ./ --report report.json",
+	"kubectl-slice -f foo.yaml -o ./ --template '{{.kind | lower}}/{{.metadata.name}}.yaml' --sort-by-kind --kustomize",
+	"kubectl-slice -f foo.yaml --output-archive manifests.tar.gz",
+	"kubectl-slice -f foo.yaml -o ./ --template '{{.kind | lower}}.yaml' --on-conflict suffix",
 	"kubectl-slice -d ./ --recurse -o ./ --include-kind Pod,Namespace",
+	"kubectl-slice -f release.tgz --recurse -o ./",
+	"kubectl-slice -f namespaces.yaml -f 'manifests/**/deploy-*.yaml' -d ./extra -o ./",
 	"kubectl-slice -d ./ --recurse --stdout --include Pod/*",
+	"kubectl-slice -f export.ndjson --allow-json --output-format yaml -o ./",
+	"kubectl-slice -f cluster-backup.yaml -o ./backup --stream",
+	"kubectl-slice -d ./repo --recurse -o ./normalized --template '{{.__source.dir}}/{{.kind | lower}}-{{.metadata.name}}.yaml'",
 	"kubectl-slice --config config.yaml",
 }
 
-func generateExamples([]string) string {
+func generateExamples(examples []string) string {
 	var s bytes.Buffer
 	for pos, v := range examples {
 		s.WriteString(fmt.Sprintf("  %s", v))
@@ -70,21 +86,7 @@ func root() *cobra.Command {
 
 			// If no input file has been provided or it's "-", then
 			// point the app to stdin
-			if (opts.InputFile == "" || opts.InputFile == "-") && opts.InputFolder == "" {
-				opts.InputFile = os.Stdin.Name()
-
-				// Check if we're receiving data from the terminal
-				// or from piped content. Users from piped content
-				// won't see this message. Users that might have forgotten
-				// setting the flags correctly will see this message.
-				if !opts.Quiet {
-					if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeNamedPipe == 0 {
-						fmt.Fprintln(opts.Stderr, "Receiving data from the terminal. Press CTRL+D when you're done typing or CTRL+C")
-						fmt.Fprintln(opts.Stderr, "to exit without processing the content. If you're seeing this by mistake, make")
-						fmt.Fprintln(opts.Stderr, "sure the command line flags, environment variables or config file are correct.")
-					}
-				}
-			}
+			useStdinIfNoInput(&opts)
 
 			// Create a new instance. This will also perform a basic validation.
 			instance, err := slice.New(opts)
@@ -96,36 +98,88 @@ func root() *cobra.Command {
 		},
 	}
 
-	rootCommand.Flags().StringVarP(&opts.InputFile, "input-file", "f", "", "the input file used to read the initial macro YAML file; if empty or \"-\", stdin is used (exclusive with --input-folder)")
-	rootCommand.Flags().StringVarP(&opts.InputFolder, "input-folder", "d", "", "the input folder used to read the initial macro YAML files (exclusive with --input-file)")
-	rootCommand.Flags().StringSliceVar(&opts.InputFolderExt, "extensions", []string{".yaml", ".yml"}, "the extensions to look for in the input folder")
-	rootCommand.Flags().BoolVarP(&opts.Recurse, "recurse", "r", false, "if true, the input folder will be read recursively (has no effect unless used with --input-folder)")
+	addSharedFlags(rootCommand.Flags(), &opts, &configFile)
 	rootCommand.Flags().StringVarP(&opts.OutputDirectory, "output-dir", "o", "", "the output directory used to output the splitted files")
 	rootCommand.Flags().StringVarP(&opts.GoTemplate, "template", "t", slice.DefaultTemplateName, "go template used to generate the file name when creating the resource files in the output directory")
 	rootCommand.Flags().BoolVar(&opts.DryRun, "dry-run", false, "if true, no files are created, but the potentially generated files will be printed as the command output")
-	rootCommand.Flags().BoolVar(&opts.DebugMode, "debug", false, "enable debug mode")
-	rootCommand.Flags().BoolVarP(&opts.Quiet, "quiet", "q", false, "if true, no output is written to stdout/err")
-	rootCommand.Flags().StringSliceVar(&opts.IncludedKinds, "include-kind", nil, "resource kind to include in the output (singular, case insensitive, glob supported)")
-	rootCommand.Flags().StringSliceVar(&opts.ExcludedKinds, "exclude-kind", nil, "resource kind to exclude in the output (singular, case insensitive, glob supported)")
-	rootCommand.Flags().StringSliceVar(&opts.IncludedNames, "include-name", nil, "resource name to include in the output (singular, case insensitive, glob supported)")
-	rootCommand.Flags().StringSliceVar(&opts.ExcludedNames, "exclude-name", nil, "resource name to exclude in the output (singular, case insensitive, glob supported)")
-	rootCommand.Flags().StringSliceVar(&opts.Included, "include", nil, "resource name to include in the output (format <kind>/<name>, case insensitive, glob supported)")
-	rootCommand.Flags().StringSliceVar(&opts.Excluded, "exclude", nil, "resource name to exclude in the output (format <kind>/<name>, case insensitive, glob supported)")
-	rootCommand.Flags().BoolVarP(&opts.StrictKubernetes, "skip-non-k8s", "s", false, "if enabled, any YAMLs that don't contain at least an \"apiVersion\", \"kind\" and \"metadata.name\" will be excluded from the split")
-	rootCommand.Flags().BoolVar(&opts.SortByKind, "sort-by-kind", false, "if enabled, resources are sorted by Kind, a la Helm, before saving them to disk")
+	rootCommand.Flags().StringVar(&opts.OnConflict, "on-conflict", slice.ConflictAppend, "what to do when multiple resources render the same file name: \"append\" them to the same file, fail with an \"error\", add a numeric \"suffix\" to the file name, \"skip\" all but the first one or \"overwrite\" with the last one")
+	rootCommand.Flags().StringVar(&opts.Report, "report", "", "if set, a JSON report with the files generated, the resources merged into each one of them and the resources skipped is written to this path (use \"-\" for stdout)")
 	rootCommand.Flags().BoolVar(&opts.OutputToStdout, "stdout", false, "if enabled, no resource is written to disk and all resources are printed to stdout instead")
-	rootCommand.Flags().StringVarP(&configFile, "config", "c", "", "path to the config file")
-	rootCommand.Flags().BoolVar(&opts.AllowEmptyKinds, "allow-empty-kinds", false, "if enabled, resources with empty kinds don't produce an error when filtering")
-	rootCommand.Flags().BoolVar(&opts.AllowEmptyNames, "allow-empty-names", false, "if enabled, resources with empty names don't produce an error when filtering")
+	rootCommand.Flags().StringVar(&opts.OutputFormat, "output-format", "", "if set, resources are re-encoded to this format, either \"yaml\" or \"json\", instead of keeping their original contents; with \"json\", the default template uses the \".json\" extension")
 	rootCommand.Flags().BoolVar(&opts.IncludeTripleDash, "include-triple-dash", false, "if enabled, the typical \"---\" YAML separator is included at the beginning of resources sliced")
+	rootCommand.Flags().StringVar(&opts.OutputArchive, "output-archive", "", "if set, the sliced files are written to this archive instead of the output directory, the format is based on the extension: .tar.gz, .tgz, .tar or .zip (exclusive with --output-dir)")
+	rootCommand.Flags().BoolVar(&opts.Kustomize, "kustomize", false, "if enabled, a \"kustomization.yaml\" listing the files generated is created (or updated, if it exists) in the output directory and every subdirectory")
+	rootCommand.Flags().BoolVar(&opts.Stream, "stream", false, "if enabled, every file is written as soon as its resources are read instead of loading the entire input in memory first, keeping memory usage low on very large inputs; resources rendering the same file name are appended to it (can't be combined with --sort-by-kind or --output-archive)")
 	rootCommand.Flags().BoolVar(&opts.PruneOutputDir, "prune", false, "if enabled, the output directory will be pruned before writing the files")
 	rootCommand.Flags().BoolVar(&opts.RemoveFileComments, "remove-comments", false, "if enabled, comments generated by the app are removed from the sliced files (but keep comments from the original file)")
-	rootCommand.Flags().StringSliceVar(&opts.IncludedGroups, "include-group", nil, "resource kind to include in the output (singular, case insensitive, glob supported)")
-	rootCommand.Flags().StringSliceVar(&opts.ExcludedGroups, "exclude-group", nil, "resource kind to exclude in the output (singular, case insensitive, glob supported)")
-	_ = rootCommand.Flags().MarkHidden("debug")
+
+	rootCommand.CompletionOptions.HiddenDefaultCmd = true
+	rootCommand.AddCommand(joinCommand())
 	return rootCommand
 }
 
+// addSharedFlags adds the flags used to read and filter resources, shared
+// by all the commands
+func addSharedFlags(flags *pflag.FlagSet, opts *slice.Options, configFile *string) {
+	flags.StringArrayVarP(&opts.InputFiles, "input-file", "f", nil, "the input file used to read the initial macro YAML file, a folder, a glob pattern (like \"manifests/**/*.yaml\") or a .tar.gz, .tgz, .tar or .zip archive; can be repeated, and if empty or \"-\", stdin is used")
+	flags.StringVarP(&opts.InputFolder, "input-folder", "d", "", "the input folder used to read the initial macro YAML files (can be combined with --input-file)")
+	flags.StringSliceVar(&opts.InputFolderExt, "extensions", []string{".yaml", ".yml"}, "the extensions to look for in the input folder or archive")
+	flags.BoolVarP(&opts.Recurse, "recurse", "r", false, "if true, the input folder or archive will be read recursively (has no effect unless used with --input-folder or an archive)")
+	flags.BoolVar(&opts.DebugMode, "debug", false, "enable debug mode")
+	flags.BoolVarP(&opts.Quiet, "quiet", "q", false, "if true, no output is written to stdout/err")
+	flags.BoolVar(&opts.Explain, "explain", false, "if enabled, a line is printed to stderr for every resource found with the rule that included or skipped it")
+	flags.StringSliceVar(&opts.IncludedKinds, "include-kind", nil, "resource kind to include in the output (singular, case insensitive, glob supported)")
+	flags.StringSliceVar(&opts.ExcludedKinds, "exclude-kind", nil, "resource kind to exclude in the output (singular, case insensitive, glob supported)")
+	flags.StringSliceVar(&opts.IncludedNames, "include-name", nil, "resource name to include in the output (singular, case insensitive, glob supported)")
+	flags.StringSliceVar(&opts.ExcludedNames, "exclude-name", nil, "resource name to exclude in the output (singular, case insensitive, glob supported)")
+	flags.StringSliceVar(&opts.Included, "include", nil, "resource name to include in the output (format <kind>/<name>, case insensitive, glob supported)")
+	flags.StringSliceVar(&opts.Excluded, "exclude", nil, "resource name to exclude in the output (format <kind>/<name>, case insensitive, glob supported)")
+	flags.StringArrayVar(&opts.IncludedRegex, "include-regex", nil, "regular expression matched against <kind>/<name> to include resources in the output (case insensitive)")
+	flags.StringArrayVar(&opts.ExcludedRegex, "exclude-regex", nil, "regular expression matched against <kind>/<name> to exclude resources in the output (case insensitive)")
+	flags.BoolVarP(&opts.StrictKubernetes, "skip-non-k8s", "s", false, "if enabled, any YAMLs that don't contain at least an \"apiVersion\", \"kind\" and \"metadata.name\" will be excluded from the split")
+	flags.BoolVar(&opts.SortByKind, "sort-by-kind", false, "if enabled, resources are sorted by Kind, a la Helm, before saving them to disk")
+	flags.StringVarP(configFile, "config", "c", "", "path to the config file")
+	flags.BoolVar(&opts.AllowEmptyKinds, "allow-empty-kinds", false, "if enabled, resources with empty kinds don't produce an error when filtering")
+	flags.BoolVar(&opts.AllowEmptyNames, "allow-empty-names", false, "if enabled, resources with empty names don't produce an error when filtering")
+	flags.BoolVar(&opts.AllowJSON, "allow-json", false, "if enabled, JSON inputs are accepted: a single object, a top-level array of objects or newline-delimited objects, and \".json\" files are also read from folders when using the default extensions")
+	flags.BoolVar(&opts.ExpandLists, "expand-lists", false, "if enabled, resources of kind \"v1/List\", or typed lists like \"ConfigMapList\" whose items are all of the kind the list is named after, are expanded and each one of their items is sliced as an individual resource")
+	flags.StringSliceVar(&opts.IncludedGroups, "include-group", nil, "resource API group to include in the output (case insensitive, glob supported, use \"core\" for the core group)")
+	flags.StringSliceVar(&opts.ExcludedGroups, "exclude-group", nil, "resource API group to exclude in the output (case insensitive, glob supported, use \"core\" for the core group)")
+	flags.StringSliceVar(&opts.IncludedAPIVersions, "include-api-version", nil, "resource API version to include in the output (version like \"v1beta1\" or full apiVersion like \"apps/v1\", case insensitive, glob supported)")
+	flags.StringSliceVar(&opts.ExcludedAPIVersions, "exclude-api-version", nil, "resource API version to exclude in the output (version like \"v1beta1\" or full apiVersion like \"apps/v1\", case insensitive, glob supported)")
+	flags.StringVarP(&opts.LabelSelector, "selector", "l", "", "label selector to filter resources in the output, supports '=', '==', '!=', 'in', 'notin' and existence checks (e.g. -l key1=value1,key2 in (v1,v2),!key3)")
+	flags.StringSliceVar(&opts.IncludedNamespaces, "include-namespace", nil, "resource namespace to include in the output (case insensitive, glob supported, use \"<none>\" for resources without a namespace)")
+	flags.StringSliceVar(&opts.ExcludedNamespaces, "exclude-namespace", nil, "resource namespace to exclude in the output (case insensitive, glob supported, use \"<none>\" for resources without a namespace)")
+	flags.StringArrayVar(&opts.IncludedAnnotations, "include-annotation", nil, "resource annotation to include in the output (format <key> or <key>=<value>, case insensitive, glob supported); can be repeated, and values are matched whole, so helm.sh/hook=pre-install does not match \"pre-install,pre-upgrade\"")
+	flags.StringArrayVar(&opts.ExcludedAnnotations, "exclude-annotation", nil, "resource annotation to exclude in the output (format <key> or <key>=<value>, case insensitive, glob supported); can be repeated, and values are matched whole, so helm.sh/hook=pre-install does not match \"pre-install,pre-upgrade\"")
+	flags.StringArrayVar(&opts.Where, "where", nil, "expression evaluated against each resource fields, only resources matching all the expressions are included (e.g. 'spec.replicas > 1', 'has(spec.template.spec.hostNetwork)')")
+	_ = flags.MarkHidden("debug")
+}
+
+// useStdinIfNoInput points the app to stdin when no input file or folder
+// has been provided, or if the input file is "-"
+func useStdinIfNoInput(opts *slice.Options) {
+	if len(opts.InputFiles) == 1 && opts.InputFiles[0] == "-" {
+		opts.InputFiles = nil
+	}
+
+	if (opts.InputFile == "" || opts.InputFile == "-") && len(opts.InputFiles) == 0 && opts.InputFolder == "" {
+		opts.InputFile = os.Stdin.Name()
+
+		// Check if we're receiving data from the terminal
+		// or from piped content. Users from piped content
+		// won't see this message. Users that might have forgotten
+		// setting the flags correctly will see this message.
+		if !opts.Quiet {
+			if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeNamedPipe == 0 {
+				fmt.Fprintln(opts.Stderr, "Receiving data from the terminal. Press CTRL+D when you're done typing or CTRL+C")
+				fmt.Fprintln(opts.Stderr, "to exit without processing the content. If you're seeing this by mistake, make")
+				fmt.Fprintln(opts.Stderr, "sure the command line flags, environment variables or config file are correct.")
+			}
+		}
+	}
+}
+
 // envVarPrefix is the prefix used for environment variables.
 // Using underscores to ensure compatibility with the shell.
 const envVarPrefix = "KUBECTL_SLICE"
@@ -192,6 +246,15 @@ func bindCobraAndViper(cmd *cobra.Command, configFileLocation string) error {
 				for _, v := range val {
 					stringified = append(stringified, fmt.Sprintf("%v", v))
 				}
+
+				// String array flags get their values replaced as-is, since
+				// some of them, like expressions, might contain commas
+				if sv, ok := flag.Value.(pflag.SliceValue); ok && flag.Value.Type() == "stringArray" {
+					_ = sv.Replace(stringified)
+					flag.Changed = true
+					return
+				}
+
 				_ = cmd.Flags().Set(flag.Name, strings.Join(stringified, ","))
 
 			case bool:
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/patrickdappollonio/kubectl-slice/slice"
+	"github.com/spf13/cobra"
+)
+
+const (
+	joinHelpShort = "join allows you to merge multiple YAML files into a single YAML stream."
+
+	joinHelpLong = `join allows you to merge multiple YAML files into a single YAML stream, the
+reverse of slicing. The same filters used when slicing can be applied and, when
+sorting by kind, resources are sorted in the same order Helm would install them.`
+)
+
+var joinExamples = []string{
+	"kubectl-slice join -d ./ --recurse > bundle.yaml",
+	"kubectl-slice join -d ./ --recurse --sort-by-kind --source-comments -o bundle.yaml",
+	"kubectl-slice join -d ./ --recurse --exclude-kind Secret | kubectl apply -f -",
+}
+
+func joinCommand() *cobra.Command {
+	opts := slice.Options{Join: true}
+	var configFile string
+
+	joinCommand := &cobra.Command{
+		Use:           "join",
+		Short:         joinHelpShort,
+		Long:          joinHelpLong,
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Example:       generateExamples(joinExamples),
+
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return bindCobraAndViper(cmd, configFile)
+		},
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Bind to the appropriate stdout/stderr
+			opts.Stdout = cmd.OutOrStdout()
+			opts.Stderr = cmd.ErrOrStderr()
+
+			// If no input file has been provided or it's "-", then
+			// point the app to stdin
+			useStdinIfNoInput(&opts)
+
+			// Create a new instance. This will also perform a basic validation.
+			instance, err := slice.New(opts)
+			if err != nil {
+				return fmt.Errorf("validation failed: %w", err)
+			}
+
+			return instance.Execute()
+		},
+	}
+
+	addSharedFlags(joinCommand.Flags(), &opts, &configFile)
+	joinCommand.Flags().StringVarP(&opts.OutputFile, "output-file", "o", "", "the file where the joined YAML is written; if empty, it's written to stdout")
+	joinCommand.Flags().BoolVar(&opts.SourceComments, "source-comments", false, "if enabled, each resource is preceded by a \"# Source:\" comment with the path of the file it came from")
+	return joinCommand
+}
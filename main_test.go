@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -66,3 +67,88 @@ func TestMainApp(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigFileSliceFlags(t *testing.T) {
+	// String array flags loaded from a configuration file must keep their
+	// values as-is, even when they contain commas or quotes, while string
+	// slice flags split them like they do on the command line.
+	dir := t.TempDir()
+	config := filepath.Join(dir, "config.yaml")
+
+	require.NoError(t, os.WriteFile(config, []byte(`include_kind: [Ingress, "Service,Secret"]
+exclude_annotation: ["helm.sh/hook=pre-install,pre-upgrade"]
+where:
+  - metadata.annotations["kubernetes.io/ingress.class"] == "nginx,internal"
+`), 0o644))
+
+	cmd := root()
+	require.NoError(t, cmd.Flags().Parse([]string{"--config=" + config}))
+	require.NoError(t, bindCobraAndViper(cmd, config))
+
+	kinds, err := cmd.Flags().GetStringSlice("include-kind")
+	require.NoError(t, err)
+	require.Equal(t, []string{"Ingress", "Service", "Secret"}, kinds)
+
+	annotations, err := cmd.Flags().GetStringArray("exclude-annotation")
+	require.NoError(t, err)
+	require.Equal(t, []string{"helm.sh/hook=pre-install,pre-upgrade"}, annotations)
+
+	where, err := cmd.Flags().GetStringArray("where")
+	require.NoError(t, err)
+	require.Equal(t, []string{`metadata.annotations["kubernetes.io/ingress.class"] == "nginx,internal"`}, where)
+}
+
+func TestAnnotationFlagsKeepCommas(t *testing.T) {
+	// Annotation values can contain commas, so the flags must not split them
+	cmd := root()
+	require.NoError(t, cmd.Flags().Parse([]string{
+		"--exclude-annotation=helm.sh/hook=pre-install,pre-upgrade",
+		"--exclude-annotation=helm.sh/hook=test",
+	}))
+
+	excluded, err := cmd.Flags().GetStringArray("exclude-annotation")
+	require.NoError(t, err)
+	require.Equal(t, []string{"helm.sh/hook=pre-install,pre-upgrade", "helm.sh/hook=test"}, excluded)
+}
+
+func TestJoinCommand(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "app"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app", "deployment.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "namespace.yaml"), []byte(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+`), 0o644))
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := root()
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	cmd.SetArgs([]string{"join", "--input-folder=" + dir, "--recurse", "--exclude-kind=Secret", "--sort-by-kind", "--source-comments"})
+	require.NoError(t, cmd.Execute())
+
+	require.Equal(t, `# Source: `+filepath.Join(dir, "namespace.yaml")+`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+---
+# Source: `+filepath.Join(dir, "app", "deployment.yaml")+`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`, stdout.String())
+	require.Equal(t, "2 resources joined to stdout.\n", stderr.String())
+}
@@ -0,0 +1,349 @@
+package slice
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Supported archive formats, detected from the archive file extension
+const (
+	archiveTar   = "tar"
+	archiveTarGz = "tar.gz"
+	archiveZip   = "zip"
+)
+
+// archiveTime is the modification time used for every entry in the archive
+// so archives are reproducible. It's the earliest date zip files support.
+var archiveTime = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// archiveFile is a single file to be written to an archive
+type archiveFile struct {
+	name string // slash-separated path, relative to the root of the archive
+	data []byte
+}
+
+// archiveFormat returns the archive format based on the file extension
+func archiveFormat(name string) (string, error) {
+	lower := strings.ToLower(name)
+
+	switch {
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGz, nil
+
+	case strings.HasSuffix(lower, ".tar"):
+		return archiveTar, nil
+
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveZip, nil
+	}
+
+	return "", fmt.Errorf("unsupported archive format for %q: supported extensions are .tar.gz, .tgz, .tar and .zip", name)
+}
+
+// detectArchive returns the archive format of the input based on its file
+// extension or, when reading from stdin or a file without a known extension,
+// on the first bytes of its contents. An empty string is returned if the
+// input is not an archive.
+func detectArchive(name string, data []byte) string {
+	if format, err := archiveFormat(name); err == nil {
+		return format
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte("\x1f\x8b")):
+		return archiveTarGz
+
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")), bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return archiveZip
+
+	case len(data) >= 262 && string(data[257:262]) == "ustar":
+		return archiveTar
+	}
+
+	return ""
+}
+
+// loadarchive reads the archive contents for files with the given extensions
+// and returns the contents of each one of the files found, in lexical order.
+// Files inside folders in the archive are only read when recurse is enabled,
+// and each input is named after the archive and its path inside of it, like
+// "release.tgz:templates/service.yaml".
+func loadarchive(extensions []string, name, format string, data []byte, recurse bool) ([]input, error) {
+	var inputs []input
+
+	add := func(member string, isRegular bool, open func() (io.ReadCloser, error)) error {
+		member = strings.TrimPrefix(path.Clean("/"+member), "/")
+
+		if !isRegular || member == "" {
+			return nil
+		}
+
+		if path.Dir(member) != "." && !recurse {
+			return nil
+		}
+
+		if !inarray(strings.ToLower(path.Ext(member)), extensions) {
+			return nil
+		}
+
+		r, err := open()
+		if err != nil {
+			return fmt.Errorf("unable to read %q from archive %q: %w", member, name, err)
+		}
+		defer r.Close()
+
+		contents, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("unable to read %q from archive %q: %w", member, name, err)
+		}
+
+		inputs = append(inputs, input{name: name + ":" + member, path: member, archive: name, data: contents})
+		return nil
+	}
+
+	switch format {
+	case archiveTar, archiveTarGz:
+		var r io.Reader = bytes.NewReader(data)
+
+		if format == archiveTarGz {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read archive %q: %w", name, err)
+			}
+
+			defer gz.Close()
+			r = gz
+		}
+
+		tr := tar.NewReader(r)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("unable to read archive %q: %w", name, err)
+			}
+
+			open := func() (io.ReadCloser, error) { return io.NopCloser(tr), nil }
+			if err := add(header.Name, header.Typeflag == tar.TypeReg, open); err != nil {
+				return nil, err
+			}
+		}
+
+	case archiveZip:
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("unable to read archive %q: %w", name, err)
+		}
+
+		for _, f := range zr.File {
+			open := func() (io.ReadCloser, error) { return f.Open() }
+			if err := add(f.Name, f.Mode().IsRegular(), open); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported archive format %q", format)
+	}
+
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no files found in archive %q with extensions: %s", name, strings.Join(extensions, ", "))
+	}
+
+	sort.SliceStable(inputs, func(i, j int) bool {
+		return inputs[i].name < inputs[j].name
+	})
+
+	return inputs, nil
+}
+
+// archiveDirs returns the folders that need to be created in the archive
+// for the given file, from the outermost to the innermost one
+func archiveDirs(name string, seen map[string]bool) []string {
+	var dirs []string
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if seen[dir] {
+			break
+		}
+
+		seen[dir] = true
+		dirs = append([]string{dir}, dirs...)
+	}
+
+	return dirs
+}
+
+// writeArchive writes the files, in the order provided, to the writer using
+// the given format. Folders are added right before the first file inside of
+// them, and all entries use the same timestamp, owner and permissions.
+func writeArchive(w io.Writer, format string, files []archiveFile) error {
+	switch format {
+	case archiveTar:
+		return writeTar(w, files)
+
+	case archiveTarGz:
+		gz := gzip.NewWriter(w)
+
+		if err := writeTar(gz, files); err != nil {
+			return err
+		}
+
+		return gz.Close()
+
+	case archiveZip:
+		return writeZip(w, files)
+	}
+
+	return fmt.Errorf("unsupported archive format %q", format)
+}
+
+func writeTar(w io.Writer, files []archiveFile) error {
+	tw := tar.NewWriter(w)
+	seen := make(map[string]bool)
+
+	for _, f := range files {
+		for _, dir := range archiveDirs(f.name, seen) {
+			if err := tw.WriteHeader(&tar.Header{
+				Typeflag: tar.TypeDir,
+				Name:     dir + "/",
+				Mode:     folderChmod,
+				ModTime:  archiveTime,
+			}); err != nil {
+				return fmt.Errorf("unable to add folder %q to archive: %w", dir, err)
+			}
+		}
+
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     f.name,
+			Mode:     defaultChmod,
+			Size:     int64(len(f.data)),
+			ModTime:  archiveTime,
+		}); err != nil {
+			return fmt.Errorf("unable to add file %q to archive: %w", f.name, err)
+		}
+
+		if _, err := tw.Write(f.data); err != nil {
+			return fmt.Errorf("unable to add file %q to archive: %w", f.name, err)
+		}
+	}
+
+	return tw.Close()
+}
+
+func writeZip(w io.Writer, files []archiveFile) error {
+	zw := zip.NewWriter(w)
+	seen := make(map[string]bool)
+
+	for _, f := range files {
+		for _, dir := range archiveDirs(f.name, seen) {
+			header := &zip.FileHeader{Name: dir + "/", Modified: archiveTime}
+			header.SetMode(os.ModeDir | folderChmod)
+
+			if _, err := zw.CreateHeader(header); err != nil {
+				return fmt.Errorf("unable to add folder %q to archive: %w", dir, err)
+			}
+		}
+
+		header := &zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: archiveTime}
+		header.SetMode(defaultChmod)
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("unable to add file %q to archive: %w", f.name, err)
+		}
+
+		if _, err := fw.Write(f.data); err != nil {
+			return fmt.Errorf("unable to add file %q to archive: %w", f.name, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// ArchiveSink writes the files to a .tar.gz, .tgz, .tar or .zip archive,
+// with the format picked from its extension. Files are kept in memory until
+// the sink is closed, and a file written more than once is replaced.
+type ArchiveSink struct {
+	Path   string    // the path of the archive
+	Stderr io.Writer // if set, a line is printed once the archive is written
+
+	files []archiveFile
+	index map[string]int // position of every file name in the files queued
+}
+
+// Write queues a file to be written to the archive
+func (a *ArchiveSink) Write(name string, data []byte, _ Metadata) error {
+	name = path.Clean(filepath.ToSlash(name))
+
+	if pos, found := a.index[name]; found {
+		a.files[pos].data = data
+		return nil
+	}
+
+	if a.index == nil {
+		a.index = make(map[string]int)
+	}
+
+	a.files = append(a.files, archiveFile{name: name, data: data})
+	a.index[name] = len(a.files) - 1
+	return nil
+}
+
+// Close writes all the queued files to the archive. The archive is first
+// written to a temporary file which is then renamed, so there's never a
+// partially written archive in its final location.
+func (a *ArchiveSink) Close() error {
+	format, err := archiveFormat(a.Path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.Path), folderChmod); err != nil {
+		return fmt.Errorf("unable to create output folder for archive %q: %w", a.Path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(a.Path), ".kubectl-slice-*")
+	if err != nil {
+		return fmt.Errorf("unable to create archive %q: %w", a.Path, err)
+	}
+
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := writeArchive(tmp, format, a.files); err != nil {
+		return fmt.Errorf("unable to write archive %q: %w", a.Path, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write archive %q: %w", a.Path, err)
+	}
+
+	if err := os.Chmod(tmp.Name(), defaultChmod); err != nil {
+		return fmt.Errorf("unable to write archive %q: %w", a.Path, err)
+	}
+
+	if err := os.Rename(tmp.Name(), a.Path); err != nil {
+		return fmt.Errorf("unable to write archive %q: %w", a.Path, err)
+	}
+
+	if fi, err := os.Stat(a.Path); err == nil {
+		printf(a.Stderr, "Wrote %s -- %d bytes.", a.Path, fi.Size())
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package slice
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_archiveFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "out.tar.gz", want: archiveTarGz},
+		{name: "out.TGZ", want: archiveTarGz},
+		{name: "nested/out.tar", want: archiveTar},
+		{name: "out.zip", want: archiveZip},
+		{name: "out.gz", wantErr: true},
+		{name: "out", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := archiveFormat(tt.name)
+			requireErrorIf(t, tt.wantErr, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// readArchive returns the names of the entries in the archive, in order,
+// alongside the contents of the non-folder entries
+func readArchive(t *testing.T, format string, data []byte) ([]string, map[string]string) {
+	t.Helper()
+
+	var names []string
+	contents := make(map[string]string)
+
+	if format == archiveZip {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+			require.True(t, f.Modified.Equal(archiveTime), "unexpected modification time for %q: %s", f.Name, f.Modified)
+
+			if f.FileInfo().IsDir() {
+				continue
+			}
+
+			rc, err := f.Open()
+			require.NoError(t, err)
+			b, err := io.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+			contents[f.Name] = string(b)
+		}
+
+		return names, contents
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if format == archiveTarGz {
+		gz, err := gzip.NewReader(r)
+		require.NoError(t, err)
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		names = append(names, header.Name)
+		require.True(t, header.ModTime.Equal(archiveTime), "unexpected modification time for %q: %s", header.Name, header.ModTime)
+
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		b, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		contents[header.Name] = string(b)
+	}
+
+	return names, contents
+}
+
+func TestExecute_outputArchive(t *testing.T) {
+	input := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+`
+
+	tests := []struct {
+		name   string
+		format string
+	}{
+		{name: "out.tar.gz", format: archiveTarGz},
+		{name: "out.tgz", format: archiveTarGz},
+		{name: "out.tar", format: archiveTar},
+		{name: "out.zip", format: archiveZip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tdinput := t.TempDir()
+			tdoutput := t.TempDir()
+
+			require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+			// Generate the archive twice to ensure the output is reproducible
+			var generated [][]byte
+			for i := 0; i < 2; i++ {
+				archive := filepath.Join(tdoutput, "nested", tt.name)
+
+				s, err := New(Options{
+					GoTemplate:    "{{.kind | lower}}/{{.metadata.name}}.yaml",
+					InputFile:     filepath.Join(tdinput, "input.yaml"),
+					OutputArchive: archive,
+					SortByKind:    true,
+					Kustomize:     true,
+					Stderr:        io.Discard,
+					Stdout:        io.Discard,
+				})
+				require.NoError(t, err)
+				require.NoError(t, s.Execute())
+
+				data, err := os.ReadFile(archive)
+				require.NoError(t, err)
+				generated = append(generated, data)
+			}
+
+			require.Equal(t, generated[0], generated[1], "archives should be reproducible")
+
+			names, contents := readArchive(t, tt.format, generated[0])
+			require.Equal(t, []string{
+				"namespace/",
+				"namespace/prod.yaml",
+				"service/",
+				"service/web.yaml",
+				"deployment/",
+				"deployment/web.yaml",
+				"namespace/kustomization.yaml",
+				"kustomization.yaml",
+				"service/kustomization.yaml",
+				"deployment/kustomization.yaml",
+			}, names)
+
+			require.Equal(t, "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n", contents["service/web.yaml"])
+			require.Equal(t, "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n  - namespace\n  - service\n  - deployment\n", contents["kustomization.yaml"])
+
+			// Nothing other than the archive should be written
+			entries, err := os.ReadDir(filepath.Join(tdoutput, "nested"))
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+		})
+	}
+}
+
+func Test_detectArchive(t *testing.T) {
+	var tarball bytes.Buffer
+	require.NoError(t, writeArchive(&tarball, archiveTar, []archiveFile{{name: "foo.yaml", data: []byte("foo: bar\n")}}))
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "release.tgz", want: archiveTarGz},
+		{name: "release.zip", want: archiveZip},
+		{name: "stdin", data: []byte("\x1f\x8b\x08\x00"), want: archiveTarGz},
+		{name: "stdin", data: []byte("PK\x03\x04"), want: archiveZip},
+		{name: "stdin", data: tarball.Bytes(), want: archiveTar},
+		{name: "stdin", data: []byte("apiVersion: v1\nkind: Pod\n")},
+		{name: "manifest.yaml", data: []byte("apiVersion: v1\nkind: Pod\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, detectArchive(tt.name, tt.data))
+		})
+	}
+}
+
+func Test_loadarchive(t *testing.T) {
+	files := []archiveFile{
+		{name: "top.yml", data: []byte("kind: Namespace\n")},
+		{name: "mychart/templates/service.yaml", data: []byte("kind: Service\n")},
+		{name: "mychart/Chart.yaml", data: []byte("name: mychart\n")},
+		{name: "mychart/README.md", data: []byte("# mychart\n")},
+		{name: "./another.yaml", data: []byte("kind: Pod\n")},
+	}
+
+	tests := []struct {
+		name       string
+		extensions []string
+		recurse    bool
+		want       []input
+		wantErr    bool
+	}{
+		{
+			name:       "top level only",
+			extensions: []string{".yaml", ".yml"},
+			want: []input{
+				{name: "release:another.yaml", path: "another.yaml", archive: "release", data: []byte("kind: Pod\n")},
+				{name: "release:top.yml", path: "top.yml", archive: "release", data: []byte("kind: Namespace\n")},
+			},
+		},
+		{
+			name:       "recursive",
+			extensions: []string{".yaml", ".yml"},
+			recurse:    true,
+			want: []input{
+				{name: "release:another.yaml", path: "another.yaml", archive: "release", data: []byte("kind: Pod\n")},
+				{name: "release:mychart/Chart.yaml", path: "mychart/Chart.yaml", archive: "release", data: []byte("name: mychart\n")},
+				{name: "release:mychart/templates/service.yaml", path: "mychart/templates/service.yaml", archive: "release", data: []byte("kind: Service\n")},
+				{name: "release:top.yml", path: "top.yml", archive: "release", data: []byte("kind: Namespace\n")},
+			},
+		},
+		{
+			name:       "no files with the extensions",
+			extensions: []string{".json"},
+			recurse:    true,
+			wantErr:    true,
+		},
+	}
+
+	for _, format := range []string{archiveTar, archiveTarGz, archiveZip} {
+		var buf bytes.Buffer
+		require.NoError(t, writeArchive(&buf, format, files))
+
+		for _, tt := range tests {
+			t.Run(format+" "+tt.name, func(t *testing.T) {
+				t.Parallel()
+
+				got, err := loadarchive(tt.extensions, "release", format, buf.Bytes(), tt.recurse)
+				requireErrorIf(t, tt.wantErr, err)
+				require.Equal(t, tt.want, got)
+			})
+		}
+	}
+
+	t.Run("corrupted archive", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := loadarchive([]string{".yaml"}, "release.tgz", archiveTarGz, []byte("\x1f\x8bnot really gzip"), true)
+		require.Error(t, err)
+	})
+}
+
+func TestExecute_inputArchive(t *testing.T) {
+	files := []archiveFile{
+		{name: "mychart/templates/all.yaml", data: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\n")},
+		{name: "mychart/Chart.yaml", data: []byte("name: mychart\nversion: 1.0.0\n")},
+	}
+
+	tdinput := t.TempDir()
+
+	var buf bytes.Buffer
+	require.NoError(t, writeArchive(&buf, archiveTarGz, files))
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "release.tgz"), buf.Bytes(), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	s, err := New(Options{
+		GoTemplate:       DefaultTemplateName,
+		InputFile:        filepath.Join(tdinput, "release.tgz"),
+		OutputDirectory:  filepath.Join(tdinput, "out"),
+		Recurse:          true,
+		DryRun:           true,
+		Explain:          true,
+		StrictKubernetes: true,
+		Stdout:           &stdout,
+		Stderr:           &stderr,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Execute())
+
+	archive := filepath.Join(tdinput, "release.tgz")
+	require.Contains(t, stderr.String(), "document 1 ["+archive+":mychart/templates/all.yaml:1] Service/web: included (no rule excluded it) -> service-web.yaml")
+	require.Contains(t, stderr.String(), "document 2 ["+archive+":mychart/templates/all.yaml:6] ConfigMap/web: included")
+}
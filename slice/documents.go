@@ -0,0 +1,101 @@
+package slice
+
+import (
+	"fmt"
+	"io"
+)
+
+// Metadata is the Kubernetes metadata of a resource. Fields missing from
+// the resource are left empty.
+type Metadata struct {
+	APIVersion  string
+	Kind        string
+	Name        string
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Document is a single resource found in the input
+type Document struct {
+	Index    int      // the number of the document in the input, as shown by the explain mode
+	Source   string   // the file the resource was read from, or "input" if it was read from Options.Input, followed by the path inside the archive for archive members
+	Line     int      // the line of the source where the resource starts
+	Filename string   // the file name the resource is written to, relative to the output directory, empty when joining
+	Metadata Metadata // the Kubernetes metadata of the resource
+	Data     []byte   // the contents of the resource, converted to the output format, if any
+}
+
+func (k kubeObjectMeta) metadata() Metadata {
+	return Metadata{
+		APIVersion:  k.APIVersion,
+		Kind:        k.Kind,
+		Name:        k.Name,
+		Namespace:   k.Namespace,
+		Labels:      k.Labels,
+		Annotations: k.Annotations,
+	}
+}
+
+// Documents reads all the resources from the reader and returns the ones
+// included by the filters in the options, with the file name rendered for
+// each one of them. Nothing is written to disk or stdout, so the options
+// describing the output, like the output directory, are ignored. If no
+// template is set, the default one is used, and unless Stderr is set,
+// messages like the ones printed by Explain are discarded.
+func Documents(r io.Reader, opts Options) ([]Document, error) {
+	if r == nil {
+		return nil, fmt.Errorf("input reader is required")
+	}
+
+	if opts.GoTemplate == "" {
+		opts.GoTemplate = DefaultTemplateName
+	}
+
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+
+	opts.Input = r
+
+	s, err := newSplit(opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Documents()
+}
+
+// Documents scans the input and returns the resources included by the
+// filters, without writing anything to disk or stdout. Resources are
+// returned in the order they'd be written: grouped by the file they're
+// written to, with files in the order they were first found or sorted by
+// kind, if requested. File name conflicts are handled according to the
+// strategy selected, so resources skipped or overwritten aren't returned.
+func (s *Split) Documents() ([]Document, error) {
+	listing := s.listing
+	s.listing = true
+	defer func() { s.listing = listing }()
+
+	if err := s.scan(); err != nil {
+		return nil, err
+	}
+
+	s.sort()
+
+	var documents []Document
+	for _, v := range s.filesFound {
+		for _, doc := range v.documents {
+			documents = append(documents, Document{
+				Index:    doc.index,
+				Source:   doc.source,
+				Line:     doc.line,
+				Filename: v.filename,
+				Metadata: doc.meta.metadata(),
+				Data:     v.data[doc.start:doc.end:doc.end],
+			})
+		}
+	}
+
+	return documents, nil
+}
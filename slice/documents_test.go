@@ -0,0 +1,264 @@
+package slice
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocuments(t *testing.T) {
+	input := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: prod
+  labels:
+    app: web
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: staging
+`
+
+	tests := []struct {
+		name    string
+		opts    Options
+		input   string
+		want    []Document
+		wantErr string
+	}{
+		{
+			name:  "resources appended to the same file",
+			opts:  Options{GoTemplate: "{{.kind | lower}}.yaml", ExcludedKinds: []string{"Secret"}},
+			input: input,
+			want: []Document{
+				{
+					Index:    0,
+					Source:   "input",
+					Line:     1,
+					Filename: "service.yaml",
+					Metadata: Metadata{APIVersion: "v1", Kind: "Service", Name: "web", Namespace: "prod", Labels: map[string]string{"app": "web"}},
+					Data:     []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: prod\n  labels:\n    app: web"),
+				},
+				{
+					Index:    3,
+					Source:   "input",
+					Line:     20,
+					Filename: "service.yaml",
+					Metadata: Metadata{APIVersion: "v1", Kind: "Service", Name: "web", Namespace: "staging"},
+					Data:     []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: staging"),
+				},
+				{
+					Index:    1,
+					Source:   "input",
+					Line:     9,
+					Filename: "deployment.yaml",
+					Metadata: Metadata{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "prod"},
+					Data:     []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: prod"),
+				},
+			},
+		},
+		{
+			name:  "file names with suffixes",
+			opts:  Options{GoTemplate: "{{.metadata.name}}.yaml", OnConflict: ConflictSuffix, IncludedKinds: []string{"Service"}},
+			input: input,
+			want: []Document{
+				{
+					Index:    0,
+					Source:   "input",
+					Line:     1,
+					Filename: "web.yaml",
+					Metadata: Metadata{APIVersion: "v1", Kind: "Service", Name: "web", Namespace: "prod", Labels: map[string]string{"app": "web"}},
+					Data:     []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: prod\n  labels:\n    app: web"),
+				},
+				{
+					Index:    3,
+					Source:   "input",
+					Line:     20,
+					Filename: "web-2.yaml",
+					Metadata: Metadata{APIVersion: "v1", Kind: "Service", Name: "web", Namespace: "staging"},
+					Data:     []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: staging"),
+				},
+			},
+		},
+		{
+			name:  "converted to json",
+			opts:  Options{GoTemplate: DefaultTemplateName, OutputFormat: OutputFormatJSON, IncludedKinds: []string{"Secret"}},
+			input: input,
+			want: []Document{
+				{
+					Index:    2,
+					Source:   "input",
+					Line:     15,
+					Filename: "secret-web.json",
+					Metadata: Metadata{APIVersion: "v1", Kind: "Secret", Name: "web"},
+					Data:     []byte("{\n  \"apiVersion\": \"v1\",\n  \"kind\": \"Secret\",\n  \"metadata\": {\n    \"name\": \"web\"\n  }\n}"),
+				},
+			},
+		},
+		{
+			name:  "output options are ignored",
+			opts:  Options{GoTemplate: DefaultTemplateName, OutputToStdout: true, OutputDirectory: "out", IncludedKinds: []string{"Deployment"}},
+			input: input,
+			want: []Document{
+				{
+					Index:    1,
+					Source:   "input",
+					Line:     9,
+					Filename: "deployment-web.yaml",
+					Metadata: Metadata{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "prod"},
+					Data:     []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  namespace: prod"),
+				},
+			},
+		},
+		{
+			name:  "metadata, labels and annotations that aren't maps",
+			opts:  Options{GoTemplate: "{{.kind | lower}}.yaml"},
+			input: "apiVersion: v1\nkind: ConfigMap\nmetadata: foo\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: web\n  labels: foo\n  annotations: [bar]\n",
+			want: []Document{
+				{
+					Index:    0,
+					Source:   "input",
+					Line:     1,
+					Filename: "configmap.yaml",
+					Metadata: Metadata{APIVersion: "v1", Kind: "ConfigMap"},
+					Data:     []byte("apiVersion: v1\nkind: ConfigMap\nmetadata: foo"),
+				},
+				{
+					Index:    1,
+					Source:   "input",
+					Line:     5,
+					Filename: "secret.yaml",
+					Metadata: Metadata{APIVersion: "v1", Kind: "Secret", Name: "web"},
+					Data:     []byte("apiVersion: v1\nkind: Secret\nmetadata:\n  name: web\n  labels: foo\n  annotations: [bar]"),
+				},
+			},
+		},
+		{
+			name:    "conflicts are errors",
+			opts:    Options{GoTemplate: "{{.metadata.name}}.yaml", OnConflict: ConflictError},
+			input:   input,
+			wantErr: `file name "web.yaml" rendered for YAML file number 1 at input:9 (kind "Deployment", name "web") is already used by YAML file number 0 at input:1 (kind "Service", name "web")`,
+		},
+		{
+			name:    "invalid template",
+			opts:    Options{GoTemplate: "{{.kind"},
+			input:   input,
+			wantErr: "file name template parse failed",
+		},
+		{
+			name:    "empty input",
+			opts:    Options{GoTemplate: DefaultTemplateName},
+			wantErr: "no data found in input file or folder",
+		},
+		{
+			name:    "input file and reader",
+			opts:    Options{GoTemplate: DefaultTemplateName, InputFile: "input.yaml"},
+			input:   input,
+			wantErr: "cannot specify both an input reader and an input file or folder",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout bytes.Buffer
+			tt.opts.Stdout = &stdout
+			tt.opts.Stderr = io.Discard
+
+			got, err := Documents(strings.NewReader(tt.input), tt.opts)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+			require.Empty(t, stdout.String(), "nothing should be written to stdout")
+		})
+	}
+}
+
+func TestDocuments_zeroOptions(t *testing.T) {
+	got, err := Documents(strings.NewReader("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"), Options{})
+	require.NoError(t, err)
+	require.Equal(t, []Document{
+		{
+			Source:   "input",
+			Line:     1,
+			Filename: "service-web.yaml",
+			Metadata: Metadata{APIVersion: "v1", Kind: "Service", Name: "web"},
+			Data:     []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web"),
+		},
+	}, got)
+}
+
+func TestDocuments_archive(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeArchive(&buf, archiveTarGz, []archiveFile{
+		{name: "templates/service.yaml", data: []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n")},
+	}))
+
+	got, err := Documents(&buf, Options{GoTemplate: "{{.__source.dir}}/{{.kind | lower}}.yaml", Recurse: true, Stderr: io.Discard})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	require.Equal(t, "input:templates/service.yaml", got[0].Source)
+	require.Equal(t, filepath.Join("templates", "service.yaml"), got[0].Filename)
+}
+
+func TestSplit_Documents(t *testing.T) {
+	tdinput := t.TempDir()
+	tdoutput := filepath.Join(t.TempDir(), "out")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: prod\n"), 0o644))
+
+	for _, stream := range []bool{false, true} {
+		s, err := New(Options{
+			GoTemplate:      DefaultTemplateName,
+			InputFile:       filepath.Join(tdinput, "input.yaml"),
+			OutputDirectory: tdoutput,
+			SortByKind:      !stream,
+			Stream:          stream,
+			Stdout:          io.Discard,
+			Stderr:          io.Discard,
+		})
+		require.NoError(t, err)
+
+		got, err := s.Documents()
+		require.NoError(t, err)
+
+		var names []string
+		for _, doc := range got {
+			names = append(names, doc.Filename)
+		}
+
+		if stream {
+			require.Equal(t, []string{"pod-web.yaml", "namespace-prod.yaml"}, names)
+		} else {
+			require.Equal(t, []string{"namespace-prod.yaml", "pod-web.yaml"}, names)
+		}
+
+		_, err = os.Stat(tdoutput)
+		require.True(t, os.IsNotExist(err), "listing documents should not write any files")
+	}
+}
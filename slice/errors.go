@@ -7,6 +7,7 @@ import (
 
 type strictModeSkipErr struct {
 	fieldName string
+	meta      kubeObjectMeta
 }
 
 func (s *strictModeSkipErr) Error() string {
@@ -16,12 +17,20 @@ func (s *strictModeSkipErr) Error() string {
 }
 
 type skipErr struct {
-	name string
-	kind string
+	name   string
+	kind   string
+	reason string
+	meta   kubeObjectMeta
 }
 
 func (e *skipErr) Error() string {
-	return fmt.Sprintf("resource %s %q is configured to be skipped", e.kind, e.name)
+	msg := fmt.Sprintf("resource %s %q is configured to be skipped", e.kind, e.name)
+
+	if e.reason != "" {
+		msg += ": " + e.reason
+	}
+
+	return msg
 }
 
 const nonK8sHelper = `the file has no Kubernetes metadata: it is most likely a non-Kubernetes YAML file, you can skip it with --skip-non-k8s`
@@ -29,6 +38,7 @@ const nonK8sHelper = `the file has no Kubernetes metadata: it is most likely a n
 type cantFindFieldErr struct {
 	fieldName string
 	fileCount int
+	location  string // where the file starts in the input, like " at foo.yaml:12"
 	meta      kubeObjectMeta
 }
 
@@ -36,8 +46,8 @@ func (e *cantFindFieldErr) Error() string {
 	var sb strings.Builder
 
 	sb.WriteString(fmt.Sprintf(
-		"unable to find Kubernetes %q field in file %d",
-		e.fieldName, e.fileCount,
+		"unable to find Kubernetes %q field in file %d%s",
+		e.fieldName, e.fileCount, e.location,
 	))
 
 	if e.meta.empty() {
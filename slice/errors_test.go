@@ -12,6 +12,32 @@ func TestErrorsInterface(t *testing.T) {
 	require.Implementsf(t, (*error)(nil), &cantFindFieldErr{}, "cantFindFieldErr should implement error")
 }
 
+func TestSkipErr_Error(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *skipErr
+		want string
+	}{
+		{
+			name: "without reason",
+			err:  &skipErr{kind: "kind/name", name: "Pod/nginx"},
+			want: `resource kind/name "Pod/nginx" is configured to be skipped`,
+		},
+		{
+			name: "with reason",
+			err:  &skipErr{kind: "group", name: "batch", reason: `excluded by group pattern "batch"`},
+			want: `resource group "batch" is configured to be skipped: excluded by group pattern "batch"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, tt.err.Error())
+		})
+	}
+}
+
 func requireErrorIf(t *testing.T, wantErr bool, err error) {
 	if wantErr {
 		require.Error(t, err)
@@ -7,6 +7,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"unicode"
 )
 
 const (
@@ -14,35 +16,93 @@ const (
 	defaultChmod = 0o664
 )
 
+// Strategies to handle multiple resources rendering the same file name
+const (
+	ConflictAppend    = "append"    // append the resource to the existing file, using "---" as separator
+	ConflictError     = "error"     // fail, reporting both resources
+	ConflictSuffix    = "suffix"    // add a counter before the file extension, like "foo-2.yaml"
+	ConflictSkip      = "skip"      // keep the first resource and skip the rest
+	ConflictOverwrite = "overwrite" // keep the last resource and discard the previous ones
+)
+
+var conflictStrategies = []string{ConflictAppend, ConflictError, ConflictSuffix, ConflictSkip, ConflictOverwrite}
+
+// processSingleFile parses a single document from the input and merges the
+// resources found in it into the files found
 func (s *Split) processSingleFile(file []byte) error {
-	s.log.Printf("Found a new YAML file in buffer, number %d", s.fileCount)
+	return s.mergeDocument(s.parseDocument(file))
+}
+
+// parseDocument parses a single document from the input, rendering the file
+// name of every resource in it. It doesn't modify the files found, so it can
+// run concurrently on copies of the instance, each one describing the
+// document being parsed.
+func (s *Split) parseDocument(file []byte) parsedDocument {
+	// Blank lines before the document are skipped when tracking the line
+	// it starts at
+	trimmed := bytes.TrimLeftFunc(file, unicode.IsSpace)
+	s.line += bytes.Count(file[:len(file)-len(trimmed)], []byte("\n"))
+	file = bytes.TrimSpace(trimmed)
+
+	s.log.Printf("Found a new YAML file in buffer, number %d%s", s.fileCount, at(s.source, s.line))
+
+	doc := parsedDocument{index: s.fileCount, source: s.source, line: s.line}
 
 	// If there's no data in the buffer, return without doing anything
 	// but count the file
-	file = bytes.TrimSpace(file)
-
 	if len(file) == 0 {
 		// If it is the first file, it means the original file started
 		// with "---", which is valid YAML, but we don't count it
 		// as a file.
 		if s.fileCount == 1 {
 			s.log.Println("Got empty file. Skipping.")
-			return nil
 		}
 
-		return nil
+		doc.empty = true
+		return doc
+	}
+
+	doc.err = s.parseResources(file, &doc.resources)
+	return doc
+}
+
+// parseResources parses the resources in the document, adding them to the
+// list provided. Lists are expanded into their items, if requested.
+func (s *Split) parseResources(file []byte, resources *[]parsedResource) error {
+	// If the user wants to expand lists, check if this is one and if so
+	// process each one of its items as if they were individual files
+	if s.opts.ExpandLists {
+		items, err := expandList(file)
+		if err != nil {
+			return fmt.Errorf("unable to expand list in YAML file number %d%s: %w", s.fileCount, at(s.source, s.line), err)
+		}
+
+		if items != nil {
+			s.log.Printf("File %d is a list, expanding %d %s", s.fileCount, len(items), pluralize("item", len(items)))
+			for _, item := range items {
+				if item = bytes.TrimSpace(item); len(item) == 0 {
+					continue
+				}
+
+				if err := s.parseResources(item, resources); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
 	}
 
 	// Send it for processing
 	meta, err := s.parseYAMLManifest(file)
 	if err != nil {
-		switch err.(type) {
+		switch e := err.(type) {
 		case *skipErr:
-			s.log.Printf("Skipping file %d: %s", s.fileCount, err.Error())
+			*resources = append(*resources, parsedResource{file: yamlFile{meta: e.meta}, skipped: e.reason})
 			return nil
 
 		case *strictModeSkipErr:
-			s.log.Printf("Skipping file %d: %s", s.fileCount, err.Error())
+			*resources = append(*resources, parsedResource{file: yamlFile{meta: e.meta}, skipped: "strict mode: " + err.Error()})
 			return nil
 
 		default:
@@ -50,34 +110,225 @@ func (s *Split) processSingleFile(file []byte) error {
 		}
 	}
 
-	existentData, position := []byte(nil), -1
-	for pos := 0; pos < len(s.filesFound); pos++ {
-		if s.filesFound[pos].filename == meta.filename {
-			existentData = s.filesFound[pos].data
-			position = pos
-			break
+	// Re-encode the resource if the user requested a specific format
+	if s.opts.OutputFormat != "" {
+		converted, err := convertDocument(file, s.opts.OutputFormat)
+		if err != nil {
+			return fmt.Errorf("unable to convert YAML file number %d%s to %s: %w", s.fileCount, at(s.source, s.line), s.opts.OutputFormat, err)
 		}
+
+		file = converted
 	}
 
-	if position == -1 {
-		s.log.Printf("Got nonexistent file. Adding it to the list: %s", meta.filename)
+	meta.data = file
+	*resources = append(*resources, parsedResource{file: meta})
+	return nil
+}
+
+// mergeDocument merges the resources of a parsed document into the files
+// found. Documents must be merged in the order they were read.
+func (s *Split) mergeDocument(doc parsedDocument) error {
+	if doc.empty {
+		s.explain(sourceDocument{index: doc.index, source: doc.source, line: doc.line}, "skipped (empty document)")
+		return nil
+	}
+
+	for _, resource := range doc.resources {
+		document := sourceDocument{index: doc.index, source: doc.source, line: doc.line, meta: resource.file.meta}
+		if err := s.mergeResource(document, resource); err != nil {
+			return err
+		}
+	}
+
+	return doc.err
+}
+
+// mergeResource adds a single resource to the files found, handling any
+// file name conflicts according to the strategy selected
+func (s *Split) mergeResource(document sourceDocument, resource parsedResource) error {
+	meta, file := resource.file, resource.file.data
+
+	if resource.skipped != "" {
+		s.log.Printf("Skipping file %d: %s", document.index, resource.skipped)
+		s.skip(document, resource.skipped)
+		s.explain(document, "skipped ("+resource.skipped+")")
+		return nil
+	}
+
+	// When joining, every resource is kept on its own, since no files
+	// are created
+	document.end = len(file)
+
+	if s.opts.Join {
+		s.explain(document, explainIncluded(meta))
 		s.filesFound = append(s.filesFound, yamlFile{
-			filename: meta.filename,
-			meta:     meta.meta,
-			data:     file,
+			meta:      meta.meta,
+			data:      file,
+			documents: []sourceDocument{document},
+		})
+		return nil
+	}
+
+	// Check if another file was already rendered to the same file name
+	// and handle the conflict according to the strategy selected
+	position := s.findFile(meta.filename)
+
+	// A file name given by the suffix strategy wasn't rendered by any
+	// resource, so a resource actually rendering it takes it over, and the
+	// suffixed file is moved to the next name available. Streamed files
+	// are already written, so they can't be moved.
+	if rendered, found := s.suffixed[meta.filename]; found && position != -1 && !s.opts.Stream {
+		s.moveSuffixedFile(position, rendered, document)
+		position = -1
+	}
+
+	if position != -1 {
+		first := s.filesFound[position].documents[0]
+
+		switch s.opts.OnConflict {
+		case ConflictError:
+			return fmt.Errorf(
+				"file name %q rendered for YAML file number %d%s (kind %q, name %q) is already used by YAML file number %d%s (kind %q, name %q)",
+				meta.filename, document.index, at(document.source, document.line), meta.meta.Kind, meta.meta.Name, first.index, at(first.source, first.line), first.meta.Kind, first.meta.Name,
+			)
+
+		case ConflictSkip:
+			reason := fmt.Sprintf("file name %q is already used by YAML file number %d", meta.filename, first.index)
+			s.log.Printf("Skipping file %d: %s", document.index, reason)
+			s.skip(document, reason)
+			s.explain(document, "skipped ("+reason+")")
+			return nil
+
+		case ConflictSuffix:
+			name := s.nextAvailableName(meta.filename)
+			s.log.Printf("Got existent file. Renaming %s to %s", meta.filename, name)
+
+			if s.opts.Explain {
+				meta.reasons = append(meta.reasons, fmt.Sprintf("renamed from %q to avoid a file name conflict", meta.filename))
+			}
+
+			if s.suffixed == nil {
+				s.suffixed = make(map[string]string)
+			}
+
+			s.suffixed[name] = meta.filename
+			meta.filename, position = name, -1
+		}
+	}
+
+	s.explain(document, explainIncluded(meta))
+
+	switch {
+	case position == -1:
+		s.log.Printf("Got nonexistent file. Adding it to the list: %s", meta.filename)
+		position = s.addFile(yamlFile{
+			filename:  meta.filename,
+			meta:      meta.meta,
+			data:      file,
+			documents: []sourceDocument{document},
 		})
-	} else {
+
+		return s.stream(position, file, false)
+
+	case s.opts.OnConflict == ConflictOverwrite:
+		s.log.Printf("Got existent file. Overwriting original buffer: %s", meta.filename)
+
+		// Keep track of the documents being replaced, since they won't
+		// be written anywhere
+		for _, v := range s.filesFound[position].documents {
+			s.skipped = append(s.skipped, skippedDocument{
+				sourceDocument: v,
+				reason:         fmt.Sprintf("file name %q overwritten by YAML file number %d", meta.filename, document.index),
+			})
+		}
+
+		s.filesFound[position] = yamlFile{
+			filename:  meta.filename,
+			meta:      meta.meta,
+			data:      file,
+			documents: []sourceDocument{document},
+		}
+
+		return s.stream(position, file, false)
+
+	case s.opts.OutputFormat == OutputFormatJSON:
+		first := s.filesFound[position].documents[0]
+		return fmt.Errorf(
+			"file name %q rendered for YAML file number %d%s (kind %q, name %q) is already used by YAML file number %d%s (kind %q, name %q): JSON files can only have one resource, use a different conflict strategy",
+			meta.filename, document.index, at(document.source, document.line), meta.meta.Kind, meta.meta.Name, first.index, at(first.source, first.line), first.meta.Kind, first.meta.Name,
+		)
+
+	default:
 		s.log.Printf("Got existent file. Appending to original buffer: %s", meta.filename)
-		existentData = append(existentData, []byte("\n---\n")...)
+		existent := s.filesFound[position]
+		existentData := append(existent.data, []byte("\n---\n")...)
+		document.start, document.end = len(existentData), len(existentData)+len(file)
 		existentData = append(existentData, file...)
 		s.filesFound[position] = yamlFile{
-			filename: meta.filename,
-			meta:     meta.meta,
-			data:     existentData,
+			filename:  meta.filename,
+			meta:      meta.meta,
+			data:      existentData,
+			documents: append(existent.documents, document),
+			size:      existent.size,
+			digest:    existent.digest,
 		}
+
+		return s.stream(position, file, true)
+	}
+}
+
+// addFile adds a new file to the files found, returning its position
+func (s *Split) addFile(v yamlFile) int {
+	if s.fileIndex == nil {
+		s.fileIndex = make(map[string]int)
 	}
 
-	return nil
+	s.filesFound = append(s.filesFound, v)
+	s.fileIndex[v.filename] = len(s.filesFound) - 1
+	return len(s.filesFound) - 1
+}
+
+// findFile returns the position of the file with the given name in the list
+// of files found, or -1 if there's none
+func (s *Split) findFile(name string) int {
+	if pos, found := s.fileIndex[name]; found {
+		return pos
+	}
+
+	return -1
+}
+
+// moveSuffixedFile renames the file at the given position, created by the
+// suffix strategy for a resource rendering the given name, to the next name
+// available, so the document being merged can use its current name
+func (s *Split) moveSuffixedFile(position int, rendered string, document sourceDocument) {
+	from := s.filesFound[position].filename
+	to := s.nextAvailableName(rendered)
+	s.log.Printf("File %s is rendered by YAML file number %d. Renaming suffixed file to %s", from, document.index, to)
+
+	for _, v := range s.filesFound[position].documents {
+		s.explain(v, fmt.Sprintf("renamed from %q to %q since YAML file number %d renders that file name", from, to, document.index))
+	}
+
+	delete(s.fileIndex, from)
+	delete(s.suffixed, from)
+	s.filesFound[position].filename = to
+	s.fileIndex[to] = position
+	s.suffixed[to] = rendered
+}
+
+// nextAvailableName adds a counter before the extension of the file name,
+// like "foo-2.yaml", until the name isn't used by any other file
+func (s *Split) nextAvailableName(name string) string {
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if s.findFile(candidate) == -1 {
+			return candidate
+		}
+	}
 }
 
 func (s *Split) scan() error {
@@ -86,76 +337,206 @@ func (s *Split) scan() error {
 	// to files
 	s.fileCount = 0
 	s.filesFound = make([]yamlFile, 0)
+	s.fileIndex = make(map[string]int)
+	s.suffixed = make(map[string]string)
+	s.skipped = nil
+
+	// Documents are parsed concurrently, but merged in the order they
+	// were read
+	s.startPipeline()
+	defer s.stopPipeline()
+
+	// Each input is scanned independently so the resources found can be
+	// traced back to the file they came from
+	for _, in := range s.inputs {
+		s.log.Printf("Scanning input %q", in.name)
+		s.source, s.path = in.name, in.path
+		if s.path == "" {
+			s.path = filepath.ToSlash(filepath.Base(in.name))
+		}
+
+		if in.open != nil {
+			if err := s.scanStream(in); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if s.opts.AllowJSON && isJSON(in.data) {
+			if err := s.scanJSON(in.data); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := s.scanInput(bytes.NewReader(in.data)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.mergeParsed(0); err != nil {
+		return err
+	}
+
+	s.log.Printf(
+		"Finished processing buffer. Generated %d individual files, and processed %d files in the original YAML.",
+		len(s.filesFound), s.fileCount,
+	)
 
+	return nil
+}
+
+func (s *Split) scanInput(r io.Reader) error {
 	// We can totally create a single decoder then decode using that, however,
 	// we want to maintain 1:1 exactly the same declaration as the YAML originally
 	// fed by the user, so we split and save copies of these resources locally.
 	// If we re-marshal the YAML, it might lose the format originally provided
 	// by the user.
-	scanner := bufio.NewReader(s.data)
+	scanner := bufio.NewReader(r)
 
 	// Create a local buffer to read files line by line
 	local := bytes.Buffer{}
 
+	// Keep track of the line being read and the one where the
+	// current document started
+	line, start := 0, 1
+
+	// Directives, like "%YAML 1.2", can only show up before a document
+	// starts, and a document explicitly ended with "..." is followed by
+	// either a new document or a "---" marker
+	prologue, ended := true, false
+
 	// Parse a single file
 	parseFile := func() error {
 		contents := local.Bytes()
 		local = bytes.Buffer{}
-		return s.processSingleFile(contents)
+		s.line = start
+		return s.submit(contents)
 	}
 
 	// Iterate over the entire buffer
 	for {
 		// Grab a single line
-		line, err := scanner.ReadString('\n')
+		text, err := scanner.ReadString('\n')
+		line++
+
 		// Find if there's an error
-		if err != nil {
-			// If we reached the end of file, handle up to this point
-			if err == io.EOF {
-				s.log.Println("Reached end of file while parsing. Sending remaining buffer to process.")
-				local.WriteString(line)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("unable to read YAML file number %d%s: %w", s.fileCount, at(s.source, line), err)
+		}
 
-				if err := parseFile(); err != nil {
-					return err
-				}
+		// Files might start with a byte order mark
+		if line == 1 {
+			text = strings.TrimPrefix(text, string(utf8BOM))
+		}
 
-				s.fileCount++
-				break
+		switch kind, rest := parseMarker(text); {
+		case kind == documentStart && ended:
+			// The previous document was already sent to process, so the
+			// marker starts the next one, which keeps any comments found
+			// in between
+			s.log.Println("Found the start of a file after the end of the previous one.")
+			if len(bytes.TrimSpace(local.Bytes())) == 0 {
+				local = bytes.Buffer{}
+				start = line + 1
 			}
 
-			// Otherwise handle the unexpected error
-			return fmt.Errorf("unable to read YAML file number %d: %w", s.fileCount, err)
-		}
+			if rest != "" {
+				if local.Len() == 0 {
+					start = line
+				}
+				local.WriteString(rest)
+			}
+
+			prologue, ended = false, false
 
-		// Check if we're at the end of the file
-		if line == "---\n" || line == "---\r\n" {
+		case kind == documentStart:
 			s.log.Println("Found the end of a file. Sending buffer to process.")
 			if err := parseFile(); err != nil {
 				return err
 			}
 			s.fileCount++
-			continue
+			start = line + 1
+
+			// Content after the marker, like a comment, a tag or
+			// a block scalar indicator, is part of the new document
+			if rest != "" {
+				start = line
+				local.WriteString(rest)
+			}
+
+			prologue = false
+
+		case kind == documentEnd:
+			s.log.Println("Found an explicit end of a file. Sending buffer to process.")
+			if err := parseFile(); err != nil {
+				return err
+			}
+			s.fileCount++
+			start = line + 1
+			prologue, ended = true, true
+
+		case prologue && isDirective(text):
+			s.log.Printf("Skipping YAML directive %q", strings.TrimSpace(text))
+			if local.Len() == 0 {
+				start = line + 1
+			}
+
+		default:
+			if !isBlankOrComment(text) {
+				prologue, ended = false, false
+			}
+
+			local.WriteString(text)
 		}
 
-		fmt.Fprint(&local, line)
-	}
+		// If we reached the end of file, handle up to this point
+		if err == io.EOF {
+			s.log.Println("Reached end of file while parsing. Sending remaining buffer to process.")
+			if err := parseFile(); err != nil {
+				return err
+			}
 
-	s.log.Printf(
-		"Finished processing buffer. Generated %d individual files, and processed %d files in the original YAML.",
-		len(s.filesFound), s.fileCount,
-	)
+			s.fileCount++
+			break
+		}
+	}
 
 	return nil
 }
 
 func (s *Split) store() error {
+	if err := s.prepareOutput(); err != nil {
+		return err
+	}
+
+	// Now write those files to the sink (or if dry-run is on, print what it
+	// would write). Files will be overwritten.
+	for _, v := range s.filesFound {
+		s.log.Printf("Handling file %q: %d bytes long.", v.filename, len(v.data))
+
+		if err := s.sink.Write(v.filename, s.fileContents(v), v.meta.metadata()); err != nil {
+			return err
+		}
+	}
+
+	return s.finishOutput()
+}
+
+// prepareOutput gets the output directory and the sink ready before any
+// file is written
+func (s *Split) prepareOutput() error {
 	// Handle output directory being empty
-	if s.opts.OutputDirectory == "" {
+	if s.opts.OutputDirectory == "" && s.opts.Sink == nil {
 		s.opts.OutputDirectory = "."
 	}
 
+	s.sink = s.outputSink()
+
 	// If the user wants to prune the output directory, do it
-	if s.opts.PruneOutputDir && !s.opts.OutputToStdout && !s.opts.DryRun {
+	if s.opts.PruneOutputDir && !s.opts.OutputToStdout && !s.opts.DryRun && s.opts.Sink == nil {
 		// Check if the directory exists and if it does, prune it
 		if _, err := os.Stat(s.opts.OutputDirectory); !os.IsNotExist(err) {
 			s.log.Printf("Pruning output directory %q", s.opts.OutputDirectory)
@@ -166,69 +547,73 @@ func (s *Split) store() error {
 		}
 	}
 
-	// Now save those files to disk (or if dry-run is on, print what it would
-	// save). Files will be overwritten.
-	s.fileCount = 0
-	for _, v := range s.filesFound {
-		s.fileCount++
-
-		fullpath := filepath.Join(s.opts.OutputDirectory, v.filename)
-		fileLength := len(v.data)
-
-		s.log.Printf("Handling file %q: %d bytes long.", fullpath, fileLength)
-
-		switch {
-		case s.opts.DryRun:
-			s.WriteStderr("Would write %s -- %d bytes.", fullpath, fileLength)
-			continue
-
-		case s.opts.OutputToStdout:
-			if s.fileCount != 1 {
-				s.WriteStdout("---")
-			}
-
-			if !s.opts.RemoveFileComments {
-				s.WriteStdout("# File: %s (%d bytes)", fullpath, fileLength)
-			}
-
-			s.WriteStdout("%s", v.data)
-			continue
+	return nil
+}
 
-		default:
-			local := make([]byte, 0, len(v.data)+4)
-
-			// If the user wants to include the triple dash, add it
-			// at the beginning of the file
-			if s.opts.IncludeTripleDash && !bytes.Equal(v.data, []byte("---")) {
-				local = append([]byte("---\n"), v.data...)
-			} else {
-				local = append(local, v.data...)
-			}
+// finishOutput writes the kustomization files, if requested, once all the
+// files are known, closes the sink and prints a summary
+func (s *Split) finishOutput() error {
+	count := len(s.filesFound)
 
-			// do nothing, handling below
-			if err := s.writeToFile(fullpath, local); err != nil {
-				return err
-			}
+	if s.opts.Kustomize {
+		if err := s.writeKustomizations(); err != nil {
+			return err
+		}
+	}
 
-			s.WriteStderr("Wrote %s -- %d bytes.", fullpath, len(local))
-			continue
+	if c, ok := s.sink.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return err
 		}
 	}
 
 	switch {
 	case s.opts.DryRun:
-		s.WriteStderr("%d %s generated (dry-run)", s.fileCount, pluralize("file", s.fileCount))
+		s.WriteStderr("%d %s generated (dry-run)", count, pluralize("file", count))
 
 	case s.opts.OutputToStdout:
-		s.WriteStderr("%d %s parsed to stdout.", s.fileCount, pluralize("file", s.fileCount))
+		s.WriteStderr("%d %s parsed to stdout.", count, pluralize("file", count))
 
 	default:
-		s.WriteStderr("%d %s generated.", s.fileCount, pluralize("file", s.fileCount))
+		s.WriteStderr("%d %s generated.", count, pluralize("file", count))
 	}
 
 	return nil
 }
 
+// fileContents returns the contents of the file as they're written to disk
+func (s *Split) fileContents(v yamlFile) []byte {
+	local := make([]byte, 0, len(v.data)+5)
+
+	// If the user wants to include the triple dash, add it
+	// at the beginning of the file
+	if s.opts.IncludeTripleDash && !bytes.Equal(v.data, []byte("---")) {
+		local = append(local, "---\n"...)
+	}
+
+	local = append(local, v.data...)
+
+	// Check if the last character is a newline, and if not, add one
+	if !bytes.HasSuffix(local, []byte{'\n'}) {
+		local = append(local, '\n')
+	}
+
+	return local
+}
+
+// skip records a document that was skipped and the reason why
+func (s *Split) skip(document sourceDocument, reason string) {
+	// When streaming, skipped documents are only kept for the report
+	if s.opts.Stream && s.opts.Report == "" {
+		return
+	}
+
+	s.skipped = append(s.skipped, skippedDocument{
+		sourceDocument: document,
+		reason:         reason,
+	})
+}
+
 func (s *Split) sort() {
 	if s.opts.SortByKind {
 		s.filesFound = sortYAMLsByKind(s.filesFound)
@@ -238,47 +623,27 @@ func (s *Split) sort() {
 // Execute runs the process according to the split.Options provided. This will
 // generate the files in the given directory.
 func (s *Split) Execute() error {
+	if s.opts.Stream {
+		return s.executeStream()
+	}
+
 	if err := s.scan(); err != nil {
 		return err
 	}
 
 	s.sort()
 
-	return s.store()
-}
-
-func (s *Split) writeToFile(path string, data []byte) error {
-	// Since a single Go Template File Name might render different folder prefixes,
-	// we need to ensure they're all created.
-	if err := os.MkdirAll(filepath.Dir(path), folderChmod); err != nil {
-		return fmt.Errorf("unable to create output folder for file %q: %w", path, err)
-	}
-
-	// Open the file as read/write, create the file if it doesn't exist, and if
-	// it does, truncate it.
-	s.log.Printf("Opening file path %q for writing", path)
-	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, defaultChmod)
-	if err != nil {
-		return fmt.Errorf("unable to create/open file %q: %w", path, err)
-	}
-
-	defer f.Close()
-
-	// Check if the last character is a newline, and if not, add one
-	if !bytes.HasSuffix(data, []byte{'\n'}) {
-		s.log.Printf("Adding new line to end of contents (content did not end on a line break)")
-		data = append(data, '\n')
+	if s.opts.Join {
+		return s.join()
 	}
 
-	// Write the entire file buffer back to the file in disk
-	if _, err := f.Write(data); err != nil {
-		return fmt.Errorf("unable to write file contents for file %q: %w", path, err)
+	if err := s.store(); err != nil {
+		return err
 	}
 
-	// Attempt to close the file cleanly
-	if err := f.Close(); err != nil {
-		return fmt.Errorf("unable to close file after write for file %q: %w", path, err)
-	}
+	return s.writeReport()
+}
 
-	return nil
+func (s *Split) writeToFile(path string, data []byte) error {
+	return writeFile(s.log, path, data)
 }
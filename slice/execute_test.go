@@ -1,14 +1,18 @@
 package slice
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"text/template"
 
 	local "github.com/patrickdappollonio/kubectl-slice/slice/template"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 func TestExecute_processSingleFile(t *testing.T) {
@@ -147,6 +151,194 @@ metadata:
 `,
 		},
 		// ----------------------------------------------------------------
+		{
+			name: "label selector matching",
+			fields: Options{
+				LabelSelector: "app=web,tier!=cache",
+			},
+			fileInput: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  labels:
+    app: web
+    tier: frontend
+`,
+			fileOutput: &yamlFile{
+				filename: "service-web.yaml",
+				meta: kubeObjectMeta{
+					APIVersion: "v1",
+					Kind:       "Service",
+					Name:       "web",
+				},
+			},
+		},
+		{
+			name: "label selector not matching",
+			fields: Options{
+				LabelSelector: "app=web,tier!=cache",
+			},
+			fileInput: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web-cache
+  labels:
+    app: web
+    tier: cache
+`,
+		},
+		{
+			name: "label selector on resource without labels",
+			fields: Options{
+				LabelSelector: "app",
+			},
+			fileInput: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`,
+		},
+		{
+			name: "invalid label selector",
+			fields: Options{
+				LabelSelector: "app in web",
+			},
+			wantFilterErr: true,
+		},
+		// ----------------------------------------------------------------
+		{
+			name: "include namespace glob",
+			fields: Options{
+				IncludedNamespaces: []string{"kube-*"},
+			},
+			fileInput: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: coredns
+  namespace: kube-system
+`,
+			fileOutput: &yamlFile{
+				filename: "configmap-coredns.yaml",
+				meta: kubeObjectMeta{
+					APIVersion: "v1",
+					Kind:       "ConfigMap",
+					Name:       "coredns",
+					Namespace:  "kube-system",
+				},
+			},
+		},
+		{
+			name: "include namespace skipping other namespaces",
+			fields: Options{
+				IncludedNamespaces: []string{"kube-*"},
+			},
+			fileInput: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  namespace: production
+`,
+		},
+		{
+			name: "include namespace skipping cluster-scoped resources",
+			fields: Options{
+				IncludedNamespaces: []string{"production"},
+			},
+			fileInput: `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: production
+`,
+		},
+		{
+			name: "include cluster-scoped resources only",
+			fields: Options{
+				IncludedNamespaces: []string{"<none>"},
+			},
+			fileInput: `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: production
+`,
+			fileOutput: &yamlFile{
+				filename: "namespace-production.yaml",
+				meta: kubeObjectMeta{
+					APIVersion: "v1",
+					Kind:       "Namespace",
+					Name:       "production",
+				},
+			},
+		},
+		{
+			name: "exclude cluster-scoped resources",
+			fields: Options{
+				ExcludedNamespaces: []string{"<NONE>"},
+			},
+			fileInput: `
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: admin
+`,
+		},
+		{
+			name: "exclude namespace case insensitive",
+			fields: Options{
+				ExcludedNamespaces: []string{"Production"},
+			},
+			fileInput: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+  namespace: production
+`,
+		},
+		// ----------------------------------------------------------------
+		{
+			name: "exclude helm hooks by annotation",
+			fields: Options{
+				ExcludedAnnotations: []string{"helm.sh/hook=*"},
+			},
+			fileInput: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: test-connection
+  annotations:
+    helm.sh/hook: test
+`,
+		},
+		{
+			name: "include by annotation",
+			fields: Options{
+				IncludedAnnotations: []string{"argocd.argoproj.io/sync-wave"},
+			},
+			fileInput: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: nginx
+  annotations:
+    argocd.argoproj.io/sync-wave: "5"
+`,
+			fileOutput: &yamlFile{
+				filename: "pod-nginx.yaml",
+				meta: kubeObjectMeta{
+					APIVersion: "v1",
+					Kind:       "Pod",
+					Name:       "nginx",
+				},
+			},
+		},
+		// ----------------------------------------------------------------
 		{
 			name:      "invalid YAML",
 			fields:    Options{},
@@ -208,6 +400,443 @@ kind: "Namespace
 	}
 }
 
+func TestExecute_sourceLocation(t *testing.T) {
+	first := "---\n# The namespace\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: prod\n---\n\n\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"
+	second := "apiVersion: v1\r\nkind: ConfigMap\r\nmetadata:\r\n  name: web\r\n---\r\napiVersion: v1\r\nkind: Secret\r\nmetadata:\r\n  name: web\r\n"
+
+	tests := []struct {
+		name    string
+		inputs  []input
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "documents across inputs",
+			inputs: []input{
+				{name: "first.yaml", data: []byte(first)},
+				{name: "second.yaml", data: []byte(second)},
+			},
+			want: []string{
+				"first.yaml/namespace-2.yaml",
+				"first.yaml/service-10.yaml",
+				"second.yaml/configmap-1.yaml",
+				"second.yaml/secret-6.yaml",
+			},
+		},
+		{
+			name: "invalid document",
+			inputs: []input{
+				{name: "first.yaml", data: []byte(first)},
+				{name: "broken.yaml", data: []byte("apiVersion: v1\nkind: Pod\n---\n\nkind: [\n")},
+			},
+			wantErr: "unable to parse YAML file number 4 at broken.yaml:5: yaml: line 1: did not find expected node content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Split{
+				opts:     Options{},
+				log:      nolog,
+				template: template.Must(template.New("split").Funcs(local.Functions).Parse("{{.__source.file}}/{{.kind | lower}}-{{.__source.line}}.yaml")),
+				inputs:   tt.inputs,
+			}
+
+			err := s.scan()
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			var got []string
+			for _, v := range s.filesFound {
+				got = append(got, v.filename)
+
+				// The source is only available to the template, not
+				// added to the resource itself
+				require.NotContains(t, string(v.data), "__source")
+				require.Equal(t, v.filename, fmt.Sprintf("%s/%s-%d.yaml", v.documents[0].source, strings.ToLower(v.meta.Kind), v.documents[0].line))
+			}
+
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExecute_explain(t *testing.T) {
+	contents := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+---
+kind: Pod
+`
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "no filters",
+			opts: Options{},
+			want: `document 0 [input.yaml:1] Deployment/web: included (no rule excluded it) -> deployment-web.yaml
+document 1 [input.yaml:8] Service/web: included (no rule excluded it) -> service-web.yaml
+document 2 [input.yaml:13] Job/migrate: included (no rule excluded it) -> job-migrate.yaml
+document 3 [input.yaml:18] Pod/<none>: included (no rule excluded it) -> pod-.yaml
+`,
+		},
+		{
+			name: "inclusion and exclusion rules",
+			opts: Options{
+				IncludedKinds:   []string{"Deployment", "Service", "Job"},
+				ExcludedGroups:  []string{"batch"},
+				LabelSelector:   "app",
+				AllowEmptyNames: true,
+			},
+			want: `document 0 [input.yaml:1] Deployment/web: included (included by kind/name pattern "Deployment/*"; label selector "app" satisfied) -> deployment-web.yaml
+document 1 [input.yaml:8] Service/web: skipped (label selector requirement "app" not satisfied)
+document 2 [input.yaml:13] Job/migrate: skipped (excluded by group pattern "batch")
+document 3 [input.yaml:18] Pod/<none>: skipped (not matched by any kind/name inclusion pattern)
+`,
+		},
+		{
+			name: "strict mode",
+			opts: Options{StrictKubernetes: true, IncludedNames: []string{"web"}},
+			want: `document 0 [input.yaml:1] Deployment/web: included (included by kind/name pattern "*/web") -> deployment-web.yaml
+document 1 [input.yaml:8] Service/web: included (included by kind/name pattern "*/web") -> service-web.yaml
+document 2 [input.yaml:13] Job/migrate: skipped (not matched by any kind/name inclusion pattern)
+document 3 [input.yaml:18] Pod/<none>: skipped (strict mode: resource does not have a Kubernetes "apiVersion" field or the field is invalid or empty)
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stderr bytes.Buffer
+			tt.opts.Explain = true
+			tt.opts.Stderr = &stderr
+
+			s := &Split{
+				opts:     tt.opts,
+				log:      nolog,
+				template: template.Must(template.New("split").Funcs(local.Functions).Parse(DefaultTemplateName)),
+				inputs:   []input{{name: "input.yaml", data: []byte(contents)}},
+			}
+
+			require.NoError(t, s.validateFilters())
+			require.NoError(t, s.scan())
+			require.Equal(t, tt.want, stderr.String())
+		})
+	}
+}
+
+func TestExecute_explainEmptyDocuments(t *testing.T) {
+	contents := `---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: token
+`
+
+	var stderr bytes.Buffer
+	s := &Split{
+		opts:     Options{Explain: true, Stderr: &stderr},
+		log:      nolog,
+		template: template.Must(template.New("split").Funcs(local.Functions).Parse(DefaultTemplateName)),
+		inputs:   []input{{name: "input.yaml", data: []byte(contents)}},
+	}
+
+	require.NoError(t, s.scan())
+	require.Equal(t, `document 0 [input.yaml:1] <none>/<none>: skipped (empty document)
+document 1 [input.yaml:2] Service/web: included (no rule excluded it) -> service-web.yaml
+document 2 [input.yaml:7] <none>/<none>: skipped (empty document)
+document 3 [input.yaml:8] Secret/token: included (no rule excluded it) -> secret-token.yaml
+`, stderr.String())
+}
+
+func TestExecute_onConflict(t *testing.T) {
+	resources := []string{
+		"apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: prod",
+		"apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: staging",
+		"apiVersion: v1\nkind: Service\nmetadata:\n  name: web-2\n  namespace: prod",
+	}
+
+	tests := []struct {
+		name        string
+		strategy    string
+		want        map[string]string
+		wantSkipped []int
+		wantErr     string
+	}{
+		{
+			name:     "append by default",
+			strategy: "",
+			want: map[string]string{
+				"service-web.yaml":   resources[0] + "\n---\n" + resources[1],
+				"service-web-2.yaml": resources[2],
+			},
+		},
+		{
+			name:     "append",
+			strategy: ConflictAppend,
+			want: map[string]string{
+				"service-web.yaml":   resources[0] + "\n---\n" + resources[1],
+				"service-web-2.yaml": resources[2],
+			},
+		},
+		{
+			name:     "error",
+			strategy: ConflictError,
+			wantErr:  `file name "service-web.yaml" rendered for YAML file number 1 (kind "Service", name "web") is already used by YAML file number 0 (kind "Service", name "web")`,
+		},
+		{
+			name:     "suffix",
+			strategy: ConflictSuffix,
+			want: map[string]string{
+				"service-web.yaml":   resources[0],
+				"service-web-3.yaml": resources[1],
+				"service-web-2.yaml": resources[2],
+			},
+		},
+		{
+			name:     "skip",
+			strategy: ConflictSkip,
+			want: map[string]string{
+				"service-web.yaml":   resources[0],
+				"service-web-2.yaml": resources[2],
+			},
+			wantSkipped: []int{1},
+		},
+		{
+			name:     "overwrite",
+			strategy: ConflictOverwrite,
+			want: map[string]string{
+				"service-web.yaml":   resources[1],
+				"service-web-2.yaml": resources[2],
+			},
+			wantSkipped: []int{0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Split{
+				opts:     Options{OnConflict: tt.strategy},
+				log:      nolog,
+				template: template.Must(template.New("split").Funcs(local.Functions).Parse(DefaultTemplateName)),
+			}
+
+			var err error
+			for pos, resource := range resources {
+				s.fileCount = pos
+				if err = s.processSingleFile([]byte(resource)); err != nil {
+					break
+				}
+			}
+
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			got := make(map[string]string)
+			for _, v := range s.filesFound {
+				got[v.filename] = string(v.data)
+			}
+			require.Equal(t, tt.want, got)
+
+			var skipped []int
+			for _, v := range s.skipped {
+				skipped = append(skipped, v.index)
+			}
+			require.Equal(t, tt.wantSkipped, skipped)
+		})
+	}
+}
+
+func TestExecute_suffixedNameRendered(t *testing.T) {
+	// The second resource gets "service-web-2.yaml" from the suffix
+	// strategy, and the third one actually renders that name
+	input := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: prod
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: staging
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web-2
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: dev
+`
+
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+	tests := []struct {
+		name        string
+		stream      bool
+		want        map[string]string
+		wantExplain string
+	}{
+		{
+			name: "suffixed file is moved",
+			want: map[string]string{
+				"service-web.yaml":   "prod",
+				"service-web-2.yaml": "",
+				"service-web-3.yaml": "staging",
+				"service-web-4.yaml": "dev",
+			},
+			wantExplain: `document 0 [input.yaml:1] Service/web: included (no rule excluded it) -> service-web.yaml
+document 1 [input.yaml:7] Service/web: included (renamed from "service-web.yaml" to avoid a file name conflict) -> service-web-2.yaml
+document 1 [input.yaml:7] Service/web: renamed from "service-web-2.yaml" to "service-web-3.yaml" since YAML file number 2 renders that file name
+document 2 [input.yaml:13] Service/web-2: included (no rule excluded it) -> service-web-2.yaml
+document 3 [input.yaml:18] Service/web: included (renamed from "service-web.yaml" to avoid a file name conflict) -> service-web-4.yaml
+`,
+		},
+		{
+			name:   "streamed files are kept",
+			stream: true,
+			want: map[string]string{
+				"service-web.yaml":     "prod",
+				"service-web-2.yaml":   "staging",
+				"service-web-2-2.yaml": "",
+				"service-web-3.yaml":   "dev",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stderr bytes.Buffer
+			sink := &MemorySink{}
+
+			s, err := New(Options{
+				InputFile:  filepath.Join(tdinput, "input.yaml"),
+				GoTemplate: DefaultTemplateName,
+				OnConflict: ConflictSuffix,
+				Stream:     tt.stream,
+				Explain:    !tt.stream,
+				Sink:       sink,
+				Stdout:     io.Discard,
+				Stderr:     &stderr,
+			})
+			require.NoError(t, err)
+			require.NoError(t, s.Execute())
+
+			got := make(map[string]string)
+			for _, f := range sink.Files {
+				var resource struct {
+					Metadata struct {
+						Namespace string `yaml:"namespace"`
+					} `yaml:"metadata"`
+				}
+
+				require.NoError(t, yaml.Unmarshal(f.Data, &resource))
+				got[f.Path] = resource.Metadata.Namespace
+			}
+
+			require.Equal(t, tt.want, got)
+
+			if tt.wantExplain != "" {
+				explain := strings.ReplaceAll(stderr.String(), filepath.Join(tdinput, "input.yaml"), "input.yaml")
+				require.True(t, strings.HasPrefix(explain, tt.wantExplain), explain)
+			}
+		})
+	}
+}
+
+func TestExecute_storeSymlinkOutsideOutputDir(t *testing.T) {
+	outside := t.TempDir()
+	tdoutput := t.TempDir()
+
+	require.NoError(t, os.Symlink(outside, filepath.Join(tdoutput, "linked")))
+	require.NoError(t, os.Symlink(filepath.Join(outside, "file.yaml"), filepath.Join(tdoutput, "file.yaml")))
+	require.NoError(t, os.Mkdir(filepath.Join(tdoutput, "regular"), 0o755))
+
+	tests := []struct {
+		name     string
+		filename string
+		wantErr  bool
+	}{
+		{name: "regular file", filename: "pod.yaml"},
+		{name: "regular folder", filename: "regular/nested/pod.yaml"},
+		{name: "symlinked folder", filename: "linked/pod.yaml", wantErr: true},
+		{name: "symlinked folder, nested", filename: "linked/nested/pod.yaml", wantErr: true},
+		{name: "symlinked file", filename: "file.yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Split{
+				opts: Options{
+					OutputDirectory: tdoutput,
+					Stderr:          io.Discard,
+				},
+				log: nolog,
+				filesFound: []yamlFile{{
+					filename: tt.filename,
+					meta:     kubeObjectMeta{Kind: "Pod", Name: "nginx"},
+					data:     []byte("kind: Pod"),
+				}},
+			}
+
+			err := s.store()
+			requireErrorIf(t, tt.wantErr, err)
+
+			if tt.wantErr {
+				require.ErrorContains(t, err, `resource Pod "nginx" resolves outside the output directory`)
+			}
+
+			entries, err := os.ReadDir(outside)
+			require.NoError(t, err)
+			require.Empty(t, entries, "no files should be written outside the output directory")
+		})
+	}
+}
+
 func TestExecute_writeToFileCases(t *testing.T) {
 	tempdir := t.TempDir()
 	s := &Split{log: nolog}
@@ -0,0 +1,247 @@
+package slice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats resources can be re-encoded to before being written
+const (
+	OutputFormatYAML = "yaml"
+	OutputFormatJSON = "json"
+)
+
+var outputFormats = []string{OutputFormatYAML, OutputFormatJSON}
+
+// utf8BOM is the byte order mark some editors add at the beginning of files
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// isJSON checks if the input looks like JSON, that is, if its first
+// non-blank character opens an object or an array
+func isJSON(data []byte) bool {
+	data = bytes.TrimLeft(bytes.TrimPrefix(data, utf8BOM), " \t\r\n")
+	return len(data) > 0 && (data[0] == '{' || data[0] == '[')
+}
+
+// scanJSON processes a JSON input, which can either be a single object, a
+// top-level array of objects, or a stream of objects like newline-delimited
+// JSON. Each object is processed as an individual document, keeping its
+// original bytes.
+func (s *Split) scanJSON(data []byte) error {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	// next returns the position where the next value starts, skipping
+	// blanks and the commas between the elements of an array
+	next := func() int {
+		pos := int(dec.InputOffset())
+		for pos < len(data) && bytes.IndexByte([]byte(" \t\r\n,"), data[pos]) >= 0 {
+			pos++
+		}
+
+		return pos
+	}
+
+	process := func() error {
+		s.line = 1 + bytes.Count(data[:next()], []byte("\n"))
+
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return fmt.Errorf("unable to parse JSON file number %d%s: %w", s.fileCount, at(s.source, s.line), err)
+		}
+
+		if err := s.submit(raw); err != nil {
+			return err
+		}
+
+		s.fileCount++
+		return nil
+	}
+
+	for {
+		pos := next()
+		if pos >= len(data) {
+			return nil
+		}
+
+		if data[pos] != '[' {
+			if err := process(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// Arrays are expanded, with each one of their elements being
+		// processed as an individual document
+		if _, err := dec.Token(); err != nil {
+			return fmt.Errorf("unable to parse JSON file number %d%s: %w", s.fileCount, at(s.source, 1+bytes.Count(data[:pos], []byte("\n"))), err)
+		}
+
+		for dec.More() {
+			if err := process(); err != nil {
+				return err
+			}
+		}
+
+		if _, err := dec.Token(); err != nil && err != io.EOF {
+			return fmt.Errorf("unable to parse JSON array%s: %w", at(s.source, s.line), err)
+		}
+	}
+}
+
+// convertDocument re-encodes a single document to the given format. Key
+// order and, when converting to YAML, comments are preserved.
+func convertDocument(contents []byte, format string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(contents, &doc); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case OutputFormatJSON:
+		var compact bytes.Buffer
+		if err := writeJSON(&compact, &doc); err != nil {
+			return nil, err
+		}
+
+		var out bytes.Buffer
+		if err := json.Indent(&out, compact.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+
+		return out.Bytes(), nil
+
+	case OutputFormatYAML:
+		blockStyle(&doc)
+
+		var out bytes.Buffer
+		enc := yaml.NewEncoder(&out)
+		enc.SetIndent(2)
+
+		if err := enc.Encode(&doc); err != nil {
+			return nil, err
+		}
+
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+
+		return bytes.TrimRight(out.Bytes(), "\n"), nil
+	}
+
+	return nil, fmt.Errorf("unsupported output format %q", format)
+}
+
+// blockStyle removes the flow style from maps and lists, as well as the
+// quotes from strings, so documents coming from JSON look like regular YAML.
+// Literal and folded strings are kept as-is, and strings that need quotes
+// are still quoted when encoded.
+func blockStyle(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode, yaml.SequenceNode:
+		node.Style &^= yaml.FlowStyle
+
+	case yaml.ScalarNode:
+		node.Style &^= yaml.DoubleQuotedStyle | yaml.SingleQuotedStyle
+	}
+
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// writeJSON writes the YAML node as compact JSON, keeping the order of the
+// keys in maps
+func writeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+
+		return writeJSON(buf, node.Content[0])
+
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias)
+
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind == yaml.AliasNode {
+				key = key.Alias
+			}
+
+			if key.Kind != yaml.ScalarNode {
+				return fmt.Errorf("line %d: only strings, numbers and booleans can be used as keys in JSON", key.Line)
+			}
+
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := marshalJSON(buf, key.Value); err != nil {
+				return err
+			}
+
+			buf.WriteByte(':')
+
+			if err := writeJSON(buf, node.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+
+	case yaml.ScalarNode:
+		// Timestamps are kept as they were written instead of being
+		// converted to Go's time format
+		if node.ShortTag() == "!!timestamp" {
+			return marshalJSON(buf, node.Value)
+		}
+
+		var value interface{}
+		if err := node.Decode(&value); err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+
+		if err := marshalJSON(buf, value); err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+	}
+
+	return nil
+}
+
+// marshalJSON writes the value as JSON without escaping HTML characters
+func marshalJSON(buf *bytes.Buffer, value interface{}) error {
+	var local bytes.Buffer
+
+	enc := json.NewEncoder(&local)
+	enc.SetEscapeHTML(false)
+
+	if err := enc.Encode(value); err != nil {
+		return err
+	}
+
+	buf.Write(bytes.TrimSuffix(local.Bytes(), []byte("\n")))
+	return nil
+}
@@ -0,0 +1,407 @@
+package slice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	local "github.com/patrickdappollonio/kubectl-slice/slice/template"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_isJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "object", input: `{"kind": "Pod"}`, want: true},
+		{name: "array", input: "\n\n  [{\"kind\": \"Pod\"}]", want: true},
+		{name: "byte order mark", input: "\xef\xbb\xbf{\"kind\": \"Pod\"}", want: true},
+		{name: "yaml", input: "kind: Pod\n"},
+		{name: "yaml starting with separator", input: "---\nkind: Pod\n"},
+		{name: "empty", input: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, isJSON([]byte(tt.input)))
+		})
+	}
+}
+
+func Test_convertDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "yaml to json keeps key order and types",
+			format: OutputFormatJSON,
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web # the name
+  labels: &labels
+    app: web
+data:
+  enabled: "true"
+  replicas: 3
+  ratio: 0.5
+  debug: false
+  empty: null
+  when: 2001-12-14
+  script: |
+    echo "a && b"
+  copy: *labels
+`,
+			want: `{
+  "apiVersion": "v1",
+  "kind": "ConfigMap",
+  "metadata": {
+    "name": "web",
+    "labels": {
+      "app": "web"
+    }
+  },
+  "data": {
+    "enabled": "true",
+    "replicas": 3,
+    "ratio": 0.5,
+    "debug": false,
+    "empty": null,
+    "when": "2001-12-14",
+    "script": "echo \"a && b\"\n",
+    "copy": {
+      "app": "web"
+    }
+  }
+}`,
+		},
+		{
+			name:   "json to yaml",
+			format: OutputFormatYAML,
+			input:  `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "web"}, "data": {"enabled": "true", "replicas": 3, "list": ["a", "b"], "script": "line 1\nline 2\n"}}`,
+			want: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+data:
+  enabled: "true"
+  replicas: 3
+  list:
+    - a
+    - b
+  script: |
+    line 1
+    line 2`,
+		},
+		{
+			name:   "yaml to yaml keeps comments",
+			format: OutputFormatYAML,
+			input: `# The service
+apiVersion: v1
+kind: Service
+metadata:
+    name: web # the name
+`,
+			want: `# The service
+apiVersion: v1
+kind: Service
+metadata:
+  name: web # the name`,
+		},
+		{
+			name:    "keys that can't be converted to json",
+			format:  OutputFormatJSON,
+			input:   "? [a, b]\n: c\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid yaml",
+			format:  OutputFormatJSON,
+			input:   "kind: [",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := convertDocument([]byte(tt.input), tt.format)
+			requireErrorIf(t, tt.wantErr, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestExecute_scanJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      []string
+		wantLines []int
+		wantErr   bool
+	}{
+		{
+			name:      "single object",
+			input:     "{\n  \"apiVersion\": \"v1\",\n  \"kind\": \"Pod\",\n  \"metadata\": {\"name\": \"web\"}\n}\n",
+			want:      []string{"{\n  \"apiVersion\": \"v1\",\n  \"kind\": \"Pod\",\n  \"metadata\": {\"name\": \"web\"}\n}"},
+			wantLines: []int{1},
+		},
+		{
+			name:      "top-level array",
+			input:     "[\n  {\"apiVersion\": \"v1\", \"kind\": \"Pod\", \"metadata\": {\"name\": \"web\"}},\n  {\"apiVersion\": \"v1\", \"kind\": \"Service\", \"metadata\": {\"name\": \"web\"}}\n]\n",
+			want:      []string{`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web"}}`, `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"}}`},
+			wantLines: []int{2, 3},
+		},
+		{
+			name:      "newline-delimited",
+			input:     "{\"apiVersion\": \"v1\", \"kind\": \"Pod\", \"metadata\": {\"name\": \"web\"}}\n\n{\"apiVersion\": \"v1\", \"kind\": \"Service\", \"metadata\": {\"name\": \"web\"}}\n",
+			want:      []string{`{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "web"}}`, `{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"}}`},
+			wantLines: []int{1, 3},
+		},
+		{
+			name:    "invalid json",
+			input:   "{\"apiVersion\": \"v1\"}\n{\"kind\": ",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Split{
+				opts:     Options{AllowJSON: true, OnConflict: ConflictSuffix},
+				log:      nolog,
+				template: template.Must(template.New("split").Funcs(local.Functions).Parse(DefaultTemplateName)),
+				inputs:   []input{{name: "input.json", data: []byte(tt.input)}},
+			}
+
+			err := s.scan()
+			requireErrorIf(t, tt.wantErr, err)
+			if tt.wantErr {
+				return
+			}
+
+			var got []string
+			var lines []int
+			for _, v := range s.filesFound {
+				got = append(got, string(v.data))
+				lines = append(lines, v.documents[0].line)
+			}
+
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.wantLines, lines)
+			require.Equal(t, len(tt.want), s.fileCount)
+		})
+	}
+}
+
+func TestExecute_outputFormat(t *testing.T) {
+	input := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web", "labels": {"app": "web"}}}
+`
+
+	tests := []struct {
+		name     string
+		opts     Options
+		filename string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "json",
+			opts:     Options{OutputFormat: OutputFormatJSON, GoTemplate: DefaultTemplateName, OnConflict: ConflictOverwrite},
+			filename: "service-web.json",
+			want: `{
+  "apiVersion": "v1",
+  "kind": "Service",
+  "metadata": {
+    "name": "web",
+    "labels": {
+      "app": "web"
+    }
+  }
+}
+`,
+		},
+		{
+			name:     "yaml",
+			opts:     Options{OutputFormat: OutputFormatYAML, GoTemplate: DefaultTemplateName},
+			filename: "service-web.yaml",
+			want: `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  labels:
+    app: web
+`,
+		},
+		{
+			name:    "invalid format",
+			opts:    Options{OutputFormat: "toml", GoTemplate: DefaultTemplateName},
+			wantErr: true,
+		},
+		{
+			name:    "json with triple dash",
+			opts:    Options{OutputFormat: OutputFormatJSON, GoTemplate: DefaultTemplateName, IncludeTripleDash: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tdinput := t.TempDir()
+			tdoutput := t.TempDir()
+
+			require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+			tt.opts.InputFile = filepath.Join(tdinput, "input.yaml")
+			tt.opts.OutputDirectory = tdoutput
+			tt.opts.Stdout = io.Discard
+			tt.opts.Stderr = io.Discard
+
+			s, err := New(tt.opts)
+			requireErrorIf(t, tt.wantErr, err)
+			if tt.wantErr {
+				return
+			}
+
+			require.NoError(t, s.Execute())
+
+			content, err := os.ReadFile(filepath.Join(tdoutput, tt.filename))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, string(content))
+		})
+	}
+}
+
+func TestExecute_outputFormatJSONConflicts(t *testing.T) {
+	input := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: prod
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+`
+
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+	tests := []struct {
+		strategy  string
+		wantFiles int
+		wantErr   string
+	}{
+		{strategy: "", wantErr: `file name "service-web.json" rendered for YAML file number 1 at ` + filepath.Join(tdinput, "input.yaml") + `:6 (kind "Service", name "web") is already used by YAML file number 0 at ` + filepath.Join(tdinput, "input.yaml") + `:1 (kind "Service", name "web"): JSON files can only have one resource, use a different conflict strategy`},
+		{strategy: ConflictAppend, wantErr: "JSON files can only have one resource, use a different conflict strategy"},
+		{strategy: ConflictError, wantErr: "is already used by YAML file number 0"},
+		{strategy: ConflictSuffix, wantFiles: 3},
+		{strategy: ConflictSkip, wantFiles: 2},
+		{strategy: ConflictOverwrite, wantFiles: 2},
+	}
+
+	for _, tt := range tests {
+		for _, stream := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%q stream %t", tt.strategy, stream), func(t *testing.T) {
+				t.Parallel()
+
+				tdoutput := t.TempDir()
+				s, err := New(Options{
+					InputFile:       filepath.Join(tdinput, "input.yaml"),
+					OutputDirectory: tdoutput,
+					OutputFormat:    OutputFormatJSON,
+					GoTemplate:      DefaultTemplateName,
+					OnConflict:      tt.strategy,
+					Stream:          stream,
+					Stdout:          io.Discard,
+					Stderr:          io.Discard,
+				})
+				require.NoError(t, err)
+
+				err = s.Execute()
+				if tt.wantErr != "" {
+					require.ErrorContains(t, err, tt.wantErr)
+					return
+				}
+
+				require.NoError(t, err)
+
+				files := readTree(t, tdoutput)
+				require.Len(t, files, tt.wantFiles)
+				for name, content := range files {
+					require.True(t, json.Valid([]byte(content)), "file %q should be valid JSON: %s", name, content)
+				}
+			})
+		}
+	}
+}
+
+func TestNew_allowJSONExtensions(t *testing.T) {
+	dir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pod.yaml"), []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "service.json"), []byte(`[{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web"}}]`), 0o644))
+
+	tests := []struct {
+		name       string
+		allowJSON  bool
+		extensions []string
+		want       int
+	}{
+		{name: "json not allowed", want: 1},
+		{name: "json allowed", allowJSON: true, want: 2},
+		{name: "json allowed with default extensions", allowJSON: true, extensions: []string{".yaml", ".yml"}, want: 2},
+		{name: "json allowed with custom extensions", allowJSON: true, extensions: []string{".yaml"}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := New(Options{
+				InputFolder:    dir,
+				InputFolderExt: tt.extensions,
+				AllowJSON:      tt.allowJSON,
+				GoTemplate:     DefaultTemplateName,
+				OutputToStdout: true,
+				Stdout:         io.Discard,
+				Stderr:         io.Discard,
+			})
+			require.NoError(t, err)
+			require.NoError(t, s.Execute())
+			require.Len(t, s.filesFound, tt.want)
+		})
+	}
+}
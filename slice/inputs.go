@@ -0,0 +1,332 @@
+package slice
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// loadInputs reads all the inputs requested, in order: the input file, then
+// every file, folder or glob pattern in the input files, and finally the
+// input folder. Files matched more than once are only read the first time.
+// If an input reader is provided, it's the only input.
+func (s *Split) loadInputs() error {
+	s.inputs = nil
+
+	exts := extensions
+	if len(s.opts.InputFolderExt) > 0 {
+		exts = s.opts.InputFolderExt
+	}
+
+	// When JSON is allowed, the default extensions also include ".json"
+	if s.opts.AllowJSON && slices.Equal(exts, extensions) {
+		exts = append(slices.Clone(exts), ".json")
+	}
+
+	if s.opts.Input != nil {
+		inputs, err := s.loadReader(s.opts.Input, "input", exts)
+		if err != nil {
+			return err
+		}
+
+		s.inputs = inputs
+		return nil
+	}
+
+	var locations []string
+	if s.opts.InputFile != "" {
+		locations = append(locations, s.opts.InputFile)
+	}
+	locations = append(locations, s.opts.InputFiles...)
+
+	seen := make(map[string]bool)
+	add := func(inputs ...input) {
+		for _, in := range inputs {
+			if id := in.id(); id != "" {
+				if seen[id] {
+					s.log.Printf("Skipping %q: already read", in.name)
+					continue
+				}
+
+				seen[id] = true
+			}
+
+			s.inputs = append(s.inputs, in)
+		}
+	}
+
+	for _, location := range locations {
+		if location == "-" || location == os.Stdin.Name() {
+			in, err := s.loadInputFile(os.Stdin.Name(), "stdin", "stdin", exts)
+			if err != nil {
+				return err
+			}
+
+			add(in...)
+			continue
+		}
+
+		// Locations that exist are read as they are, even if their names
+		// contain glob characters, and only the rest are expanded
+		fi, statErr := os.Stat(filepath.Clean(location))
+		if statErr != nil && hasGlobMeta(location) {
+			s.log.Printf("Expanding glob pattern %q", location)
+			matches, err := globFiles(location)
+			if err != nil {
+				return err
+			}
+			s.log.Printf("Found %d files matching %q", len(matches), location)
+
+			root, _ := globRoot(location)
+			for _, match := range matches {
+				rel, err := filepath.Rel(root, match)
+				if err != nil {
+					return err
+				}
+
+				in, err := s.loadInputFile(match, match, filepath.ToSlash(rel), exts)
+				if err != nil {
+					return err
+				}
+
+				add(in...)
+			}
+
+			continue
+		}
+
+		location = filepath.Clean(location)
+		if statErr == nil && fi.IsDir() {
+			s.log.Printf("Loading folder %q", location)
+			inputs, err := loadfolder(exts, location, s.opts.Recurse, s.opts.Stream)
+			if err != nil {
+				return err
+			}
+			s.log.Printf("Found %d files in folder %q", len(inputs), location)
+
+			add(inputs...)
+			continue
+		}
+
+		in, err := s.loadInputFile(location, location, filepath.Base(location), exts)
+		if err != nil {
+			return err
+		}
+
+		add(in...)
+	}
+
+	if s.opts.InputFolder != "" {
+		s.opts.InputFolder = filepath.Clean(s.opts.InputFolder)
+
+		s.log.Printf("Loading folder %q", s.opts.InputFolder)
+		inputs, err := loadfolder(exts, s.opts.InputFolder, s.opts.Recurse, s.opts.Stream)
+		if err != nil {
+			return err
+		}
+		s.log.Printf("Found %d files in folder %q", len(inputs), s.opts.InputFolder)
+
+		add(inputs...)
+	}
+
+	return nil
+}
+
+// loadInputFile reads a single file, returning its contents or, if it's an
+// archive, the contents of the files inside of it. The name and relative
+// path are the ones recorded for the input. When streaming, only the
+// beginning of the file is read to find out if it's an archive.
+func (s *Split) loadInputFile(fp, name, rel string, exts []string) ([]input, error) {
+	if s.opts.Stream {
+		s.log.Printf("Opening file %s for streaming", fp)
+		in, head, err := streamInput(fp, name, rel)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.loadStreamed(in, head, exts)
+	}
+
+	s.log.Printf("Loading file %s", fp)
+	buf, err := loadfile(fp)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.loadData(name, rel, buf.Bytes(), exts)
+}
+
+// loadReader reads the input from a reader, which, just like files, can
+// also be an archive
+func (s *Split) loadReader(r io.Reader, name string, exts []string) ([]input, error) {
+	if s.opts.Stream {
+		in, head, err := streamReader(io.NopCloser(r), name, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.loadStreamed(in, head, exts)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", name, err)
+	}
+
+	return s.loadData(name, name, data, exts)
+}
+
+// loadStreamed returns the streamed input as-is, unless it's an archive:
+// archives can't be streamed, so they're read in full
+func (s *Split) loadStreamed(in input, head []byte, exts []string) ([]input, error) {
+	if detectArchive(in.name, head) == "" {
+		return []input{in}, nil
+	}
+
+	data, err := in.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.loadData(in.name, in.path, data, exts)
+}
+
+// loadData returns the data read as an input or, if it's an archive, the
+// contents of the files inside of it
+func (s *Split) loadData(name, rel string, data []byte, exts []string) ([]input, error) {
+	format := detectArchive(name, data)
+	if format == "" {
+		return []input{{name: name, path: rel, data: data}}, nil
+	}
+
+	s.log.Printf("Reading %q as a %s archive", name, format)
+	inputs, err := loadarchive(exts, name, format, data, s.opts.Recurse)
+	if err != nil {
+		return nil, err
+	}
+	s.log.Printf("Found %d files in archive %q", len(inputs), name)
+
+	return inputs, nil
+}
+
+// hasGlobMeta checks if the location contains any of the characters used
+// by glob patterns
+func hasGlobMeta(location string) bool {
+	return strings.ContainsAny(location, "*?[")
+}
+
+// globFiles returns the files matching the glob pattern, in lexical order.
+// Besides the syntax supported by filepath.Match, a "**" path element matches
+// zero or more folders, so "manifests/**/*.yaml" matches both
+// "manifests/foo.yaml" and "manifests/bar/baz/foo.yaml".
+func globFiles(pattern string) ([]string, error) {
+	root, rest := globRoot(pattern)
+	for _, segment := range rest {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+	}
+
+	recursive := inarray("**", rest)
+
+	var matches []string
+	err := filepath.Walk(root, func(fp string, info os.FileInfo, err error) error {
+		if err != nil {
+			if fp == root && os.IsNotExist(err) {
+				return filepath.SkipAll
+			}
+
+			return err
+		}
+
+		rel, err := filepath.Rel(root, fp)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+
+		if info.IsDir() {
+			// Without a "**" there's no need to look deeper than the
+			// amount of elements in the pattern
+			if !recursive && len(parts) >= len(rest) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if info.Mode().IsRegular() && matchGlobSegments(rest, parts) {
+			matches = append(matches, fp)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to expand glob pattern %q: %w", pattern, err)
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no files found matching glob pattern %q", pattern)
+	}
+
+	return matches, nil
+}
+
+// globRoot splits the glob pattern in the folder to start searching from,
+// which is the longest portion of the pattern without glob characters, and
+// the slash-separated elements of the pattern left to match
+func globRoot(pattern string) (string, []string) {
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+
+	var base []string
+	for _, segment := range segments[:len(segments)-1] {
+		if hasGlobMeta(segment) {
+			break
+		}
+
+		base = append(base, segment)
+	}
+
+	root := "."
+	if len(base) > 0 {
+		root = filepath.FromSlash(strings.Join(base, "/"))
+		if root == "" {
+			root = string(filepath.Separator)
+		}
+	}
+
+	return root, segments[len(base):]
+}
+
+// matchGlobSegments checks if the path elements match the pattern elements,
+// where a "**" pattern element matches zero or more path elements
+func matchGlobSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchGlobSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+
+	if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+		return false
+	}
+
+	return matchGlobSegments(pattern[1:], parts[1:])
+}
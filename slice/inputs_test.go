@@ -0,0 +1,285 @@
+package slice
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_matchGlobSegments(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*.yaml", path: "foo.yaml", want: true},
+		{pattern: "*.yaml", path: "foo/bar.yaml", want: false},
+		{pattern: "*/*.yaml", path: "foo/bar.yaml", want: true},
+		{pattern: "**/deploy-*.yaml", path: "deploy-web.yaml", want: true},
+		{pattern: "**/deploy-*.yaml", path: "prod/apps/deploy-web.yaml", want: true},
+		{pattern: "**/deploy-*.yaml", path: "prod/apps/service-web.yaml", want: false},
+		{pattern: "prod/**/*.yaml", path: "prod/foo.yaml", want: true},
+		{pattern: "prod/**/*.yaml", path: "dev/foo.yaml", want: false},
+		{pattern: "**", path: "prod/apps/foo.yaml", want: true},
+		{pattern: "deploy-[ab].yaml", path: "deploy-a.yaml", want: true},
+		{pattern: "deploy-[ab].yaml", path: "deploy-c.yaml", want: false},
+		{pattern: "deploy-?.yaml", path: "deploy-a.yaml", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			got := matchGlobSegments(strings.Split(tt.pattern, "/"), strings.Split(tt.path, "/"))
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_globFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{
+		"deploy-web.yaml",
+		"service-web.yaml",
+		"prod/deploy-api.yaml",
+		"prod/apps/deploy-db.yaml",
+		"prod/apps/notes.txt",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("kind: Foo\n"), 0o644))
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "single folder",
+			pattern: "*.yaml",
+			want:    []string{"deploy-web.yaml", "service-web.yaml"},
+		},
+		{
+			name:    "double star",
+			pattern: "**/deploy-*.yaml",
+			want:    []string{"deploy-web.yaml", "prod/apps/deploy-db.yaml", "prod/deploy-api.yaml"},
+		},
+		{
+			name:    "glob in a folder name",
+			pattern: "p*/deploy-*.yaml",
+			want:    []string{"prod/deploy-api.yaml"},
+		},
+		{
+			name:    "no matches",
+			pattern: "**/*.json",
+			wantErr: true,
+		},
+		{
+			name:    "missing folder",
+			pattern: "missing/*.yaml",
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			pattern: "[*.yaml",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := globFiles(filepath.Join(dir, tt.pattern))
+			requireErrorIf(t, tt.wantErr, err)
+
+			var want []string
+			for _, v := range tt.want {
+				want = append(want, filepath.Join(dir, v))
+			}
+
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestNew_inputFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"namespace.yaml":           "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: prod\n",
+		"apps/deploy-web.yaml":     "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+		"apps/sub/deploy-db.yaml":  "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: db\n",
+		"apps/service-web.yaml":    "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		"extra/configmap-web.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\n",
+		"we[ird].yaml":             "apiVersion: v1\nkind: Secret\nmetadata:\n  name: web\n",
+	}
+
+	for name, contents := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644))
+	}
+
+	// Archives with members named like other inputs
+	for _, name := range []string{"a.tgz", "b.tgz"} {
+		var buf bytes.Buffer
+		require.NoError(t, writeArchive(&buf, archiveTarGz, []archiveFile{{name: "namespace.yaml", data: []byte(files["namespace.yaml"])}}))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0o644))
+	}
+
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+
+	reldir, err := filepath.Rel(cwd, dir)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		opts    Options
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "files, globs and folders",
+			opts: Options{
+				InputFiles: []string{
+					filepath.Join(dir, "namespace.yaml"),
+					filepath.Join(dir, "apps", "**", "deploy-*.yaml"),
+					filepath.Join(dir, "extra"),
+				},
+			},
+			want: []string{
+				"namespace.yaml",
+				"apps/deploy-web.yaml",
+				"apps/sub/deploy-db.yaml",
+				"extra/configmap-web.yaml",
+			},
+		},
+		{
+			name: "input file, input files and input folder combined",
+			opts: Options{
+				InputFile:   filepath.Join(dir, "namespace.yaml"),
+				InputFiles:  []string{filepath.Join(dir, "extra", "*.yaml")},
+				InputFolder: filepath.Join(dir, "apps"),
+			},
+			want: []string{
+				"namespace.yaml",
+				"extra/configmap-web.yaml",
+				"apps/deploy-web.yaml",
+				"apps/service-web.yaml",
+			},
+		},
+		{
+			name: "files matched more than once are read once",
+			opts: Options{
+				InputFiles: []string{
+					filepath.Join(dir, "apps", "deploy-web.yaml"),
+					filepath.Join(dir, "apps", "*.yaml"),
+				},
+				InputFolder: filepath.Join(dir, "apps"),
+				Recurse:     true,
+			},
+			want: []string{
+				"apps/deploy-web.yaml",
+				"apps/service-web.yaml",
+				"apps/sub/deploy-db.yaml",
+			},
+		},
+		{
+			name: "same file with relative and absolute paths is read once",
+			opts: Options{
+				InputFiles: []string{
+					filepath.Join(reldir, "namespace.yaml"),
+					filepath.Join(dir, "namespace.yaml"),
+					filepath.Join(dir, "a.tgz"),
+					filepath.Join(reldir, "a.tgz"),
+				},
+			},
+			want: []string{
+				"namespace.yaml",
+				"a.tgz:namespace.yaml",
+			},
+		},
+		{
+			name: "archive members named like other inputs are read",
+			opts: Options{
+				InputFiles: []string{
+					filepath.Join(dir, "a.tgz"),
+					filepath.Join(dir, "b.tgz"),
+					filepath.Join(dir, "namespace.yaml"),
+				},
+			},
+			want: []string{
+				"a.tgz:namespace.yaml",
+				"b.tgz:namespace.yaml",
+				"namespace.yaml",
+			},
+		},
+		{
+			name: "existing files with glob characters in their names are read as is",
+			opts: Options{
+				InputFiles: []string{
+					filepath.Join(dir, "we[ird].yaml"),
+					filepath.Join(dir, "extra", "*.yaml"),
+				},
+			},
+			want: []string{
+				"we[ird].yaml",
+				"extra/configmap-web.yaml",
+			},
+		},
+		{
+			name:    "missing file",
+			opts:    Options{InputFiles: []string{filepath.Join(dir, "missing.yaml")}},
+			wantErr: true,
+		},
+		{
+			name:    "glob without matches",
+			opts:    Options{InputFiles: []string{filepath.Join(dir, "*.json")}},
+			wantErr: true,
+		},
+		{
+			name:    "no input",
+			opts:    Options{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.opts.GoTemplate = DefaultTemplateName
+			tt.opts.OutputToStdout = true
+			tt.opts.Stdout = io.Discard
+			tt.opts.Stderr = io.Discard
+
+			s, err := New(tt.opts)
+			requireErrorIf(t, tt.wantErr, err)
+			if tt.wantErr {
+				return
+			}
+
+			var got []string
+			for _, in := range s.inputs {
+				name := in.name
+				if !filepath.IsAbs(name) {
+					name = filepath.Join(cwd, name)
+				}
+
+				rel, err := filepath.Rel(dir, name)
+				require.NoError(t, err)
+				got = append(got, filepath.ToSlash(rel))
+			}
+
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package slice
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// join writes all the resources found, in order, as a single YAML stream,
+// either to the output file or to stdout
+func (s *Split) join() error {
+	var buf bytes.Buffer
+
+	for pos, v := range s.filesFound {
+		if pos > 0 {
+			buf.WriteString("---\n")
+		}
+
+		if s.opts.SourceComments && len(v.documents) > 0 {
+			fmt.Fprintf(&buf, "# Source: %s\n", v.documents[0].source)
+		}
+
+		buf.Write(v.data)
+		buf.WriteString("\n")
+	}
+
+	count := len(s.filesFound)
+
+	if s.opts.OutputFile == "" {
+		fmt.Fprint(s.opts.Stdout, buf.String())
+		s.WriteStderr("%d %s joined to stdout.", count, pluralize("resource", count))
+		return nil
+	}
+
+	if err := s.writeToFile(s.opts.OutputFile, buf.Bytes()); err != nil {
+		return err
+	}
+
+	s.WriteStderr("Wrote %s -- %d bytes.", s.opts.OutputFile, buf.Len())
+	s.WriteStderr("%d %s joined.", count, pluralize("resource", count))
+	return nil
+}
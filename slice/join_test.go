@@ -0,0 +1,113 @@
+package slice
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecute_join(t *testing.T) {
+	inputs := []input{
+		{name: "a.yaml", data: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: web\n")},
+		{name: "b.yaml", data: []byte("# the namespace\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: prod")},
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			name: "keeps original order",
+			want: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+# the namespace
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+`,
+		},
+		{
+			name: "sorted by kind with source comments and filters",
+			opts: Options{SortByKind: true, SourceComments: true, ExcludedKinds: []string{"Service"}},
+			want: `# Source: b.yaml
+# the namespace
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+---
+# Source: a.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`,
+		},
+		{
+			name: "resources with the same name are kept apart",
+			opts: Options{IncludedNames: []string{"web"}},
+			want: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout bytes.Buffer
+			tt.opts.Join = true
+			tt.opts.Stdout = &stdout
+			tt.opts.Stderr = io.Discard
+
+			s := &Split{opts: tt.opts, log: nolog, inputs: inputs}
+
+			require.NoError(t, s.validateFilters())
+			require.NoError(t, s.Execute())
+			require.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestExecute_joinToFile(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "nested", "bundle.yaml")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "pod.yaml"), []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: nginx\n"), 0o644))
+
+	s, err := New(Options{
+		Join:        true,
+		InputFolder: dir,
+		OutputFile:  output,
+		Stdout:      io.Discard,
+		Stderr:      io.Discard,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Execute())
+
+	content, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "apiVersion: v1\nkind: Pod\nmetadata:\n  name: nginx\n", string(content))
+}
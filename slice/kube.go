@@ -1,22 +1,40 @@
 package slice
 
 import (
+	"hash"
 	"sort"
 	"strings"
 )
 
+// emptyNamespace is the value used to match resources without a namespace,
+// such as cluster-scoped resources, when filtering by namespace
+const emptyNamespace = "<none>"
+
+// coreGroup is the name used to match resources from the Kubernetes core
+// group, like Pods or Services, whose apiVersion has no group, like "v1"
+const coreGroup = "core"
+
 type yamlFile struct {
-	filename string
-	meta     kubeObjectMeta
-	data     []byte
+	filename  string
+	meta      kubeObjectMeta
+	data      []byte
+	reasons   []string         // rules that included the file, only set on --explain
+	documents []sourceDocument // documents from the input merged into this file
+
+	// When streaming, the data is written right away and only its size and
+	// checksum are kept, for the report
+	size   int
+	digest hash.Hash
 }
 
 type kubeObjectMeta struct {
-	APIVersion string
-	Kind       string
-	Name       string
-	Namespace  string
-	Group      string
+	APIVersion  string
+	Kind        string
+	Name        string
+	Namespace   string
+	Group       string
+	Labels      map[string]string
+	Annotations map[string]string
 }
 
 func (objectMeta *kubeObjectMeta) GetGroupFromAPIVersion() string {
@@ -28,6 +46,13 @@ func (objectMeta *kubeObjectMeta) GetGroupFromAPIVersion() string {
 	return ""
 }
 
+// GetVersionFromAPIVersion returns the version part of the apiVersion,
+// like "v1" for both "v1" and "apps/v1"
+func (objectMeta *kubeObjectMeta) GetVersionFromAPIVersion() string {
+	fields := strings.Split(objectMeta.APIVersion, "/")
+	return strings.ToLower(fields[len(fields)-1])
+}
+
 func (k kubeObjectMeta) empty() bool {
 	return k.APIVersion == "" && k.Kind == "" && k.Name == "" && k.Namespace == ""
 }
@@ -0,0 +1,204 @@
+package slice
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizationFile is the name of the kustomization file generated
+const kustomizationFile = "kustomization.yaml"
+
+// kustomizationNames are all the file names Kustomize recognizes as a
+// kustomization file, in the order Kustomize looks for them
+var kustomizationNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// kustomizeResources returns the resources the kustomization file of every
+// directory should list: the files directly inside of it and the
+// subdirectories with their own kustomization file. Resources are listed in
+// the order the files are provided, and directories are returned in the
+// order they're first found, with "." being the output directory itself.
+func kustomizeResources(files []yamlFile) ([]string, map[string][]string) {
+	var dirs []string
+	resources := make(map[string][]string)
+
+	add := func(dir, resource string) {
+		if _, found := resources[dir]; !found {
+			dirs = append(dirs, dir)
+		}
+
+		if !inarray(resource, resources[dir]) {
+			resources[dir] = append(resources[dir], resource)
+		}
+	}
+
+	for _, v := range files {
+		name := path.Clean(filepath.ToSlash(v.filename))
+		dir, base := path.Dir(name), path.Base(name)
+
+		add(dir, base)
+
+		// Ensure every parent folder lists the folder below it
+		for ; dir != "."; dir = path.Dir(dir) {
+			add(path.Dir(dir), path.Base(dir))
+		}
+	}
+
+	return dirs, resources
+}
+
+// kustomization generates the contents of a kustomization file listing the
+// resources provided. If there's an existing kustomization file, only its
+// resources are updated: the resources provided are listed first, followed
+// by any other resource that was already listed in the file, unless keep is
+// set and returns false for it.
+func kustomization(existing []byte, resources []string, keep func(resource string) bool) ([]byte, error) {
+	if len(bytes.TrimSpace(existing)) == 0 {
+		existing = []byte("apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\n")
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(existing, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expecting a YAML map")
+	}
+
+	root := doc.Content[0]
+
+	// Find the current list of resources, if any
+	var current *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "resources" {
+			current = root.Content[i+1]
+			break
+		}
+	}
+
+	if current == nil {
+		current = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "resources"}, current)
+	}
+
+	merged := append([]string(nil), resources...)
+	if current.Kind == yaml.SequenceNode {
+		for _, v := range current.Content {
+			if v.Kind == yaml.ScalarNode && !inarray(v.Value, merged) && (keep == nil || keep(v.Value)) {
+				merged = append(merged, v.Value)
+			}
+		}
+	}
+
+	*current = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	for _, v := range merged {
+		current.Content = append(current.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v})
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// remoteResource checks if a kustomization resource points to a remote
+// location, like "https://example.com/app.yaml" or a repository like
+// "github.com/org/repo/app?ref=v1", instead of a local file or folder
+func remoteResource(resource string) bool {
+	if strings.Contains(resource, "://") || strings.Contains(resource, "?") || strings.HasPrefix(resource, "git@") {
+		return true
+	}
+
+	host, _, nested := strings.Cut(resource, "/")
+	return nested && host != "." && host != ".." && strings.Contains(host, ".")
+}
+
+// existingKustomization finds the kustomization file of a directory in the
+// output directory, under any of the names Kustomize recognizes, returning
+// its name relative to the output directory and its contents. If there's
+// none, the default name is returned.
+func (s *Split) existingKustomization(dir string) (string, []byte, error) {
+	for _, v := range kustomizationNames {
+		name := filepath.Join(filepath.FromSlash(dir), v)
+		fullpath := filepath.Join(s.opts.OutputDirectory, name)
+
+		within, err := resolvesWithin(s.opts.OutputDirectory, fullpath)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to resolve path for file %q: %w", fullpath, err)
+		}
+
+		if !within {
+			return "", nil, fmt.Errorf("kustomization file %q resolves outside the output directory %q through a symlink", fullpath, s.opts.OutputDirectory)
+		}
+
+		existing, err := os.ReadFile(fullpath)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to read kustomization file %q: %w", fullpath, err)
+		}
+
+		return name, existing, nil
+	}
+
+	return filepath.Join(filepath.FromSlash(dir), kustomizationFile), nil, nil
+}
+
+// writeKustomizations generates or updates the kustomization file of the
+// output directory and every subdirectory with sliced files
+func (s *Split) writeKustomizations() error {
+	dirs, resources := kustomizeResources(s.filesFound)
+
+	for _, dir := range dirs {
+		name := filepath.Join(filepath.FromSlash(dir), kustomizationFile)
+
+		// Only kustomization files in the output directory can be updated,
+		// archives and other sinks always get them generated from scratch.
+		// Local resources listed in them that no longer exist are dropped.
+		var existing []byte
+		var keep func(string) bool
+		if s.opts.Sink == nil && s.opts.OutputArchive == "" {
+			var err error
+			if name, existing, err = s.existingKustomization(dir); err != nil {
+				return err
+			}
+
+			keep = func(resource string) bool {
+				if remoteResource(resource) {
+					return true
+				}
+
+				_, err := os.Stat(filepath.Join(s.opts.OutputDirectory, filepath.FromSlash(dir), filepath.FromSlash(resource)))
+				return !os.IsNotExist(err)
+			}
+		}
+
+		data, err := kustomization(existing, resources[dir], keep)
+		if err != nil {
+			return fmt.Errorf("unable to generate kustomization file %q: %w", filepath.Join(s.opts.OutputDirectory, name), err)
+		}
+
+		if err := s.sink.Write(name, data, Metadata{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,298 @@
+package slice
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_kustomizeResources(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string
+		wantDirs []string
+		want     map[string][]string
+	}{
+		{
+			name:     "flat",
+			files:    []string{"service-web.yaml", "deployment-web.yaml"},
+			wantDirs: []string{"."},
+			want:     map[string][]string{".": {"service-web.yaml", "deployment-web.yaml"}},
+		},
+		{
+			name:     "subfolders",
+			files:    []string{"service/web.yaml", "namespace.yaml", "deployment/web.yaml", "service/api.yaml"},
+			wantDirs: []string{"service", ".", "deployment"},
+			want: map[string][]string{
+				".":          {"service", "namespace.yaml", "deployment"},
+				"service":    {"web.yaml", "api.yaml"},
+				"deployment": {"web.yaml"},
+			},
+		},
+		{
+			name:     "nested subfolders without files in between",
+			files:    []string{"prod/apps/web.yaml"},
+			wantDirs: []string{"prod/apps", "prod", "."},
+			want: map[string][]string{
+				".":         {"prod"},
+				"prod":      {"apps"},
+				"prod/apps": {"web.yaml"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var files []yamlFile
+			for _, v := range tt.files {
+				files = append(files, yamlFile{filename: v})
+			}
+
+			dirs, resources := kustomizeResources(files)
+			require.Equal(t, tt.wantDirs, dirs)
+			require.Equal(t, tt.want, resources)
+		})
+	}
+}
+
+func Test_kustomization(t *testing.T) {
+	tests := []struct {
+		name      string
+		existing  string
+		resources []string
+		missing   []string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "new file",
+			resources: []string{"service.yaml", "deployment"},
+			want: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+  - service.yaml
+  - deployment
+`,
+		},
+		{
+			name: "existing file without resources",
+			existing: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namespace: prod # the namespace
+`,
+			resources: []string{"service.yaml"},
+			want: `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+namespace: prod # the namespace
+resources:
+  - service.yaml
+`,
+		},
+		{
+			name: "existing file with resources",
+			existing: `resources:
+- https://example.com/base.yaml
+- service.yaml
+commonLabels:
+  app: web
+`,
+			resources: []string{"deployment.yaml", "service.yaml"},
+			want: `resources:
+  - deployment.yaml
+  - service.yaml
+  - https://example.com/base.yaml
+commonLabels:
+  app: web
+`,
+		},
+		{
+			name: "existing file with missing resources",
+			existing: `resources:
+- service.yaml
+- old.yaml
+- base
+`,
+			resources: []string{"deployment.yaml"},
+			missing:   []string{"old.yaml", "base"},
+			want: `resources:
+  - deployment.yaml
+  - service.yaml
+`,
+		},
+		{
+			name:     "existing file is not a map",
+			existing: "- foo.yaml\n",
+			wantErr:  true,
+		},
+		{
+			name:     "existing file is invalid",
+			existing: "resources: [",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := kustomization([]byte(tt.existing), tt.resources, func(resource string) bool {
+				return !inarray(resource, tt.missing)
+			})
+			requireErrorIf(t, tt.wantErr, err)
+			require.Equal(t, tt.want, string(got))
+		})
+	}
+}
+
+func Test_remoteResource(t *testing.T) {
+	tests := []struct {
+		resource string
+		want     bool
+	}{
+		{resource: "service.yaml", want: false},
+		{resource: "deployment/web.yaml", want: false},
+		{resource: "../base", want: false},
+		{resource: "./overlays/prod", want: false},
+		{resource: "https://example.com/base.yaml", want: true},
+		{resource: "github.com/org/repo/base?ref=v1", want: true},
+		{resource: "github.com/org/repo/base", want: true},
+		{resource: "git@github.com:org/repo.git", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.want, remoteResource(tt.resource))
+		})
+	}
+}
+
+func TestExecute_kustomize(t *testing.T) {
+	input := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+`
+
+	tests := []struct {
+		name       string
+		sortByKind bool
+		want       map[string]string
+	}{
+		{
+			name: "input order",
+			want: map[string]string{
+				"kustomization.yaml":         "  - deployment\n  - service\n  - namespace-prod.yaml\n",
+				"service/kustomization.yaml": "  - web.yaml\n",
+			},
+		},
+		{
+			name:       "sorted by kind",
+			sortByKind: true,
+			want: map[string]string{
+				"kustomization.yaml":         "  - namespace-prod.yaml\n  - service\n  - deployment\n",
+				"service/kustomization.yaml": "  - web.yaml\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tdinput := t.TempDir()
+			tdoutput := t.TempDir()
+
+			require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+			s, err := New(Options{
+				GoTemplate:      `{{if eq .kind "Namespace"}}namespace-{{.metadata.name}}{{else}}{{.kind | lower}}/{{.metadata.name}}{{end}}.yaml`,
+				InputFile:       filepath.Join(tdinput, "input.yaml"),
+				OutputDirectory: tdoutput,
+				SortByKind:      tt.sortByKind,
+				Kustomize:       true,
+				Stderr:          io.Discard,
+				Stdout:          io.Discard,
+			})
+			require.NoError(t, err)
+			require.NoError(t, s.Execute())
+
+			for name, resources := range tt.want {
+				content, err := os.ReadFile(filepath.Join(tdoutput, name))
+				require.NoError(t, err)
+				require.Equal(t, "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n"+resources, string(content))
+			}
+		})
+	}
+}
+
+func TestExecute_kustomizeExisting(t *testing.T) {
+	tdinput := t.TempDir()
+	tdoutput := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tdoutput, "configmap.yaml"), []byte("kind: ConfigMap\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tdoutput, "kustomization.yml"), []byte(`namespace: prod
+resources:
+- https://example.com/base.yaml
+- configmap.yaml
+- deployment-web.yaml
+`), 0o644))
+
+	s, err := New(Options{
+		GoTemplate:      DefaultTemplateName,
+		InputFile:       filepath.Join(tdinput, "input.yaml"),
+		OutputDirectory: tdoutput,
+		Kustomize:       true,
+		Stderr:          io.Discard,
+		Stdout:          io.Discard,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Execute())
+
+	// The existing kustomization file is updated, whatever its name, and
+	// resources no longer found are dropped from it
+	require.NoFileExists(t, filepath.Join(tdoutput, "kustomization.yaml"))
+
+	content, err := os.ReadFile(filepath.Join(tdoutput, "kustomization.yml"))
+	require.NoError(t, err)
+	require.Equal(t, "namespace: prod\nresources:\n  - service-web.yaml\n  - https://example.com/base.yaml\n  - configmap.yaml\n", string(content))
+}
+
+func TestExecute_kustomizeReservedFileName(t *testing.T) {
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n"), 0o644))
+
+	for _, name := range kustomizationNames {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := New(Options{
+				GoTemplate:      "app/" + name,
+				InputFile:       filepath.Join(tdinput, "input.yaml"),
+				OutputDirectory: t.TempDir(),
+				Kustomize:       true,
+				Stderr:          io.Discard,
+				Stdout:          io.Discard,
+			})
+			require.NoError(t, err)
+			require.EqualError(t, s.Execute(), fmt.Sprintf("file name %q rendered for YAML file number 0 at %s:1 (kind \"Service\", name \"web\") is reserved for the kustomization files generated", filepath.Join("app", name), filepath.Join(tdinput, "input.yaml")))
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package slice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// listManifest is the minimal representation of a Kubernetes List, such as
+// "v1/List" or any of the "*List" kinds returned by "kubectl get -o yaml".
+// Items are kept as YAML nodes so they can be re-encoded preserving key
+// order and comments.
+type listManifest struct {
+	APIVersion string       `yaml:"apiVersion"`
+	Kind       string       `yaml:"kind"`
+	Items      *[]yaml.Node `yaml:"items"`
+}
+
+// isList checks if the manifest is either a generic Kubernetes "v1/List" or
+// a typed list like "ConfigMapList" or "SecretList". Since custom resources
+// can also have kinds ending in "List", like "ShoppingList", typed lists
+// must have at least one item, and all of their items must be Kubernetes
+// resources of the kind the list is named after. Typed lists returned by
+// the API server leave "kind" and "apiVersion" off their items, so items
+// without a kind are accepted as long as they have "metadata".
+func (l listManifest) isList() bool {
+	if l.Items == nil {
+		return false
+	}
+
+	if l.Kind == "List" {
+		return l.APIVersion == "v1"
+	}
+
+	kind := l.itemKind()
+	if kind == "" || len(*l.Items) == 0 {
+		return false
+	}
+
+	for _, item := range *l.Items {
+		var meta struct {
+			Kind     string      `yaml:"kind"`
+			Metadata interface{} `yaml:"metadata"`
+		}
+
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+
+		if err := item.Decode(&meta); err != nil {
+			return false
+		}
+
+		if _, ok := meta.Metadata.(map[string]interface{}); meta.Kind == "" && !ok {
+			return false
+		}
+
+		if meta.Kind != "" && meta.Kind != kind {
+			return false
+		}
+	}
+
+	return true
+}
+
+// itemKind returns the kind of the items of a typed list, like "ConfigMap"
+// for a "ConfigMapList", or an empty string if the kind isn't a typed list
+func (l listManifest) itemKind() string {
+	kind, ok := strings.CutSuffix(l.Kind, "List")
+	if !ok {
+		return ""
+	}
+
+	return kind
+}
+
+// setMissingKeys adds the keys and values given to the beginning of a
+// mapping node, skipping the keys that are already set
+func setMissingKeys(node *yaml.Node, pairs ...[2]string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	existing := make(map[string]bool, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		existing[node.Content[i].Value] = true
+	}
+
+	var missing []*yaml.Node
+	for _, pair := range pairs {
+		if pair[1] == "" || existing[pair[0]] {
+			continue
+		}
+
+		missing = append(missing,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: pair[0]},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: pair[1]},
+		)
+	}
+
+	node.Content = append(missing, node.Content...)
+}
+
+// expandList checks if the contents received are a Kubernetes List and, if
+// so, returns each one of its items encoded as an individual YAML document.
+// If the contents are not a List, a nil slice is returned so the caller can
+// handle the contents as a regular resource.
+func expandList(contents []byte) ([][]byte, error) {
+	var list listManifest
+
+	// Errors here are ignored on purpose: if the contents can't be parsed
+	// as a list, they're handled as a regular resource, and the YAML parser
+	// will report any issues with the file number attached.
+	if err := yaml.Unmarshal(contents, &list); err != nil {
+		return nil, nil
+	}
+
+	if !list.isList() {
+		return nil, nil
+	}
+
+	items := make([][]byte, 0, len(*list.Items))
+	for pos, item := range *list.Items {
+		// Items from typed lists may not have a kind or an API version of
+		// their own, so they're taken from the list
+		if kind := list.itemKind(); kind != "" {
+			setMissingKeys(&item, [2]string{"apiVersion", list.APIVersion}, [2]string{"kind", kind})
+		}
+
+		var buf bytes.Buffer
+
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+
+		if err := enc.Encode(&item); err != nil {
+			return nil, fmt.Errorf("unable to encode item %d from %s: %w", pos, list.Kind, err)
+		}
+
+		if err := enc.Close(); err != nil {
+			return nil, fmt.Errorf("unable to encode item %d from %s: %w", pos, list.Kind, err)
+		}
+
+		items = append(items, buf.Bytes())
+	}
+
+	return items, nil
+}
@@ -0,0 +1,282 @@
+package slice
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_expandList(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantNil bool
+	}{
+		{
+			name: "regular resource",
+			input: `apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+`,
+			wantNil: true,
+		},
+		{
+			name: "list kind without items",
+			input: `apiVersion: example.com/v1
+kind: ShoppingList
+metadata:
+  name: groceries
+`,
+			wantNil: true,
+		},
+		{
+			name: "custom resource with items",
+			input: `apiVersion: example.com/v1
+kind: ShoppingList
+metadata:
+  name: groceries
+items:
+- name: milk
+- name: eggs
+`,
+			wantNil: true,
+		},
+		{
+			name: "typed list with items of another kind",
+			input: `apiVersion: v1
+kind: ConfigMapList
+items:
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: foo
+`,
+			wantNil: true,
+		},
+		{
+			name: "empty typed list",
+			input: `apiVersion: v1
+kind: ConfigMapList
+items: []
+`,
+			wantNil: true,
+		},
+		{
+			name: "generic list from another api version",
+			input: `apiVersion: example.com/v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: foo
+`,
+			wantNil: true,
+		},
+		{
+			name:    "non-map document",
+			input:   `- foo`,
+			wantNil: true,
+		},
+		{
+			name: "generic list",
+			input: `apiVersion: v1
+kind: List
+metadata:
+  resourceVersion: ""
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: foo # the service
+- apiVersion: apps/v1
+  kind: Deployment
+  metadata:
+    name: bar
+    labels:
+      app: bar
+`,
+			want: []string{
+				"apiVersion: v1\nkind: Service\nmetadata:\n  name: foo # the service\n",
+				"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: bar\n  labels:\n    app: bar\n",
+			},
+		},
+		{
+			name: "typed list",
+			input: `apiVersion: v1
+kind: ConfigMapList
+items:
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: foo
+  data:
+    key: value
+`,
+			want: []string{
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: foo\ndata:\n  key: value\n",
+			},
+		},
+		{
+			name: "typed list from the api server with items without kind",
+			input: `apiVersion: v1
+kind: ConfigMapList
+metadata:
+  resourceVersion: "123"
+items:
+- metadata:
+    name: foo
+  data:
+    key: value
+- apiVersion: v1
+  kind: ConfigMap
+  metadata:
+    name: bar
+`,
+			want: []string{
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: foo\ndata:\n  key: value\n",
+				"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: bar\n",
+			},
+		},
+		{
+			name: "typed list from another api version with items without kind",
+			input: `apiVersion: apps/v1
+kind: DeploymentList
+items:
+- metadata:
+    name: web
+`,
+			want: []string{
+				"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+			},
+		},
+		{
+			name: "typed list with items without kind and of another kind",
+			input: `apiVersion: v1
+kind: ConfigMapList
+items:
+- metadata:
+    name: foo
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: bar
+`,
+			wantNil: true,
+		},
+		{
+			name: "empty list",
+			input: `apiVersion: v1
+kind: List
+items: []
+`,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := expandList([]byte(tt.input))
+			require.NoError(t, err)
+
+			if tt.wantNil {
+				require.Nil(t, got)
+				return
+			}
+
+			converted := make([]string, 0, len(got))
+			for _, v := range got {
+				converted = append(converted, string(v))
+			}
+
+			require.Equal(t, tt.want, converted)
+		})
+	}
+}
+
+func TestExpandListsEndToEnd(t *testing.T) {
+	input := `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Service
+  metadata:
+    name: foo
+- apiVersion: v1
+  kind: List
+  items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: nested
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: bar
+---
+apiVersion: v1
+kind: ConfigMapList
+items:
+- metadata:
+    name: typed
+`
+
+	cases := []struct {
+		name   string
+		expand bool
+		files  []string
+	}{
+		{
+			name:  "lists are kept as-is by default",
+			files: []string{"list-.yaml", "pod-bar.yaml", "configmaplist-.yaml"},
+		},
+		{
+			name:   "lists are expanded, including nested ones",
+			expand: true,
+			files:  []string{"service-foo.yaml", "configmap-nested.yaml", "pod-bar.yaml", "configmap-typed.yaml"},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tdinput := t.TempDir()
+			tdoutput := t.TempDir()
+
+			require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+			s, err := New(Options{
+				GoTemplate:      DefaultTemplateName,
+				InputFile:       filepath.Join(tdinput, "input.yaml"),
+				OutputDirectory: tdoutput,
+				ExpandLists:     tt.expand,
+				Stderr:          io.Discard,
+				Stdout:          io.Discard,
+			})
+			require.NoError(t, err)
+			require.NoError(t, s.Execute())
+
+			var found []string
+			for _, v := range s.filesFound {
+				found = append(found, v.filename)
+			}
+			require.Equal(t, tt.files, found)
+
+			for _, name := range tt.files {
+				content, err := os.ReadFile(filepath.Join(tdoutput, name))
+				require.NoError(t, err)
+				require.False(t, bytes.Contains(content, []byte("items:")) && tt.expand, "file %q should not contain a list", name)
+			}
+		})
+	}
+}
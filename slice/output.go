@@ -1,6 +1,9 @@
 package slice
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 func (s *Split) WriteStderr(format string, args ...interface{}) {
 	if s.opts.Quiet {
@@ -13,3 +16,48 @@ func (s *Split) WriteStderr(format string, args ...interface{}) {
 func (s *Split) WriteStdout(format string, args ...interface{}) {
 	fmt.Fprintf(s.opts.Stdout, format+"\n", args...)
 }
+
+// explain prints, when enabled, a single line for the document with its
+// index, source, kind/name and the outcome of processing it
+func (s *Split) explain(document sourceDocument, outcome string) {
+	if !s.opts.Explain {
+		return
+	}
+
+	s.WriteStderr("document %d [%s:%d] %s/%s: %s", document.index, document.source, document.line, orNone(document.meta.Kind), orNone(document.meta.Name), outcome)
+}
+
+// at describes where a document starts in its input, like " at foo.yaml:12",
+// to be added to messages about it. It's empty if the input is unknown.
+func at(source string, line int) string {
+	if source == "" {
+		return ""
+	}
+
+	return fmt.Sprintf(" at %s:%d", source, line)
+}
+
+// explainIncluded returns the outcome of an included document, listing the
+// rules that included it
+func explainIncluded(file yamlFile) string {
+	reasons := "no rule excluded it"
+	if len(file.reasons) > 0 {
+		reasons = strings.Join(file.reasons, "; ")
+	}
+
+	if file.filename == "" {
+		return fmt.Sprintf("included (%s)", reasons)
+	}
+
+	return fmt.Sprintf("included (%s) -> %s", reasons, file.filename)
+}
+
+// orNone returns the value given, or "<none>" if it's empty, to print
+// fields missing from a resource
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+
+	return s
+}
@@ -0,0 +1,144 @@
+package slice
+
+import (
+	"runtime"
+	"sync"
+)
+
+// parsedResource is a single resource found in a document, with its file
+// name rendered and its contents converted to the output format, if any
+type parsedResource struct {
+	file    yamlFile
+	skipped string // the reason the resource was skipped, if it was
+}
+
+// parsedDocument is a document from the input once parsed, which can hold
+// more than one resource when lists are expanded
+type parsedDocument struct {
+	index     int
+	source    string
+	line      int
+	resources []parsedResource
+	empty     bool
+	err       error
+}
+
+// parseJob is a document waiting to be parsed by a worker
+type parseJob struct {
+	index  int
+	source string
+	path   string
+	line   int
+	data   []byte
+	result chan parsedDocument
+}
+
+// pipeline parses documents in a pool of workers. The results are kept in
+// the order the documents were read, so they can be merged in that same
+// order regardless of which worker finishes first.
+type pipeline struct {
+	jobs    chan parseJob
+	pending []chan parsedDocument // results not merged yet, in the order the documents were read
+	window  int                   // the amount of documents that can be parsed ahead of the merge
+	wg      sync.WaitGroup
+}
+
+// workers returns the amount of documents to parse concurrently
+func (s *Split) workers() int {
+	if s.opts.Workers > 0 {
+		return s.opts.Workers
+	}
+
+	return runtime.GOMAXPROCS(0)
+}
+
+// startPipeline starts the workers parsing documents, if more than one is
+// requested: otherwise, documents are parsed and merged as they're read
+func (s *Split) startPipeline() {
+	workers := s.workers()
+	if workers < 2 {
+		return
+	}
+
+	s.log.Printf("Parsing documents with %d workers", workers)
+	p := &pipeline{
+		jobs:   make(chan parseJob, workers),
+		window: 4 * workers,
+	}
+
+	for i := 0; i < workers; i++ {
+		// Every worker has its own copy of the instance, so the fields
+		// describing the document being parsed aren't shared
+		w := *s
+
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+
+			for job := range p.jobs {
+				w.fileCount, w.source, w.path, w.line = job.index, job.source, job.path, job.line
+				job.result <- w.parseDocument(job.data)
+			}
+		}()
+	}
+
+	s.pipeline = p
+}
+
+// stopPipeline stops the workers, discarding any results not merged
+func (s *Split) stopPipeline() {
+	if s.pipeline == nil {
+		return
+	}
+
+	close(s.pipeline.jobs)
+	s.pipeline.wg.Wait()
+	s.pipeline = nil
+}
+
+// submit sends the current document to be parsed, then merges all the
+// documents already parsed. If there's no pipeline, the document is parsed
+// and merged right away.
+func (s *Split) submit(data []byte) error {
+	p := s.pipeline
+	if p == nil {
+		return s.processSingleFile(data)
+	}
+
+	result := make(chan parsedDocument, 1)
+	p.jobs <- parseJob{index: s.fileCount, source: s.source, path: s.path, line: s.line, data: data, result: result}
+	p.pending = append(p.pending, result)
+
+	return s.mergeParsed(p.window)
+}
+
+// mergeParsed merges the documents already parsed, in the order they were
+// read, waiting for them to be parsed until no more than max documents
+// are pending
+func (s *Split) mergeParsed(max int) error {
+	p := s.pipeline
+	if p == nil {
+		return nil
+	}
+
+	for len(p.pending) > 0 {
+		var doc parsedDocument
+
+		if len(p.pending) > max {
+			doc = <-p.pending[0]
+		} else {
+			select {
+			case doc = <-p.pending[0]:
+			default:
+				return nil
+			}
+		}
+
+		p.pending = p.pending[1:]
+		if err := s.mergeDocument(doc); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
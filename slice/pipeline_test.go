@@ -0,0 +1,161 @@
+package slice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecute_workers(t *testing.T) {
+	// Mix resources that are included, skipped, merged into the same file
+	// and expanded from lists, so the order they're merged in matters
+	var input strings.Builder
+	for i := 0; i < 200; i++ {
+		if i > 0 {
+			input.WriteString("---\n")
+		}
+
+		switch i % 4 {
+		case 0:
+			fmt.Fprintf(&input, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: secret-%d\n", i)
+		case 1:
+			fmt.Fprintf(&input, "apiVersion: v1\nkind: ConfigMapList\nitems:\n  - apiVersion: v1\n    kind: ConfigMap\n    metadata:\n      name: config-%d\n  - apiVersion: v1\n    kind: ConfigMap\n    metadata:\n      name: config-%d\n", i%3, i%5)
+		case 2:
+			fmt.Fprintf(&input, "# Service number %d\napiVersion: v1\nkind: Service\nmetadata:\n  name: web-%d\n", i, i%7)
+		default:
+			fmt.Fprintf(&input, "not: a resource\nnumber: %d\n", i)
+		}
+	}
+
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input.String()), 0o644))
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "append", opts: Options{OnConflict: ConflictAppend}},
+		{name: "suffix", opts: Options{OnConflict: ConflictSuffix}},
+		{name: "overwrite", opts: Options{OnConflict: ConflictOverwrite}},
+		{name: "skip", opts: Options{OnConflict: ConflictSkip}},
+		{name: "stream", opts: Options{Stream: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Parsing concurrently must print, skip and merge the
+			// resources exactly like parsing them one after the other
+			run := func(workers int) (string, string) {
+				var stdout, stderr bytes.Buffer
+
+				opts := tt.opts
+				opts.InputFile = filepath.Join(tdinput, "input.yaml")
+				opts.GoTemplate = "{{.kind | lower}}-{{.metadata.name}}.yaml"
+				opts.OutputToStdout = true
+				opts.ExpandLists = true
+				opts.StrictKubernetes = true
+				opts.ExcludedKinds = []string{"Secret"}
+				opts.Explain = true
+				opts.Workers = workers
+				opts.Stdout = &stdout
+				opts.Stderr = &stderr
+
+				s, err := New(opts)
+				require.NoError(t, err)
+				require.NoError(t, s.Execute())
+
+				return stdout.String(), stderr.String()
+			}
+
+			wantStdout, wantStderr := run(1)
+			require.Contains(t, wantStderr, "document 199 [")
+
+			for _, workers := range []int{2, 8} {
+				gotStdout, gotStderr := run(workers)
+				require.Equal(t, wantStdout, gotStdout, "stdout with %d workers", workers)
+				require.Equal(t, wantStderr, gotStderr, "stderr with %d workers", workers)
+			}
+		})
+	}
+}
+
+func TestExecute_workersError(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 100; i++ {
+		if i > 0 {
+			input.WriteString("---\n")
+		}
+
+		// Documents 30 and 60 are invalid, and the error must always be
+		// the one for the first of them
+		if i == 30 || i == 60 {
+			input.WriteString("kind: [\n")
+			continue
+		}
+
+		fmt.Fprintf(&input, "apiVersion: v1\nkind: Pod\nmetadata:\n  name: pod-%d\n", i)
+	}
+
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input.String()), 0o644))
+
+	for _, workers := range []int{1, 2, 8} {
+		t.Run(fmt.Sprintf("workers=%d", workers), func(t *testing.T) {
+			t.Parallel()
+
+			s, err := New(Options{
+				InputFile:      filepath.Join(tdinput, "input.yaml"),
+				GoTemplate:     DefaultTemplateName,
+				OutputToStdout: true,
+				Workers:        workers,
+				Stdout:         io.Discard,
+				Stderr:         io.Discard,
+			})
+			require.NoError(t, err)
+
+			err = s.Execute()
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "unable to parse YAML file number 30 at "+filepath.Join(tdinput, "input.yaml")+":151")
+			require.Nil(t, s.pipeline, "workers should be stopped")
+		})
+	}
+}
+
+// BenchmarkExecute_workers slices a generated input with 50,000 documents,
+// each one rendering a different file name, with different amounts of
+// workers parsing them
+func BenchmarkExecute_workers(b *testing.B) {
+	input := generateInput(b, 50000, 50000)
+
+	workers := []int{1, 2, 4}
+	if n := runtime.GOMAXPROCS(0); n > 4 {
+		workers = append(workers, n)
+	}
+
+	for _, n := range workers {
+		b.Run(fmt.Sprintf("workers=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				s, err := New(Options{
+					InputFile:      input,
+					GoTemplate:     DefaultTemplateName,
+					OutputToStdout: true,
+					Workers:        n,
+					Stdout:         io.Discard,
+					Stderr:         io.Discard,
+				})
+				require.NoError(b, err)
+				require.NoError(b, s.Execute())
+				require.Len(b, s.filesFound, 50000)
+			}
+		})
+	}
+}
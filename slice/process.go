@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/mb0/glob"
@@ -19,14 +20,17 @@ func (s *Split) parseYAMLManifest(contents []byte) (yamlFile, error) {
 
 	s.log.Println("Parsing YAML from buffer up to this point")
 	if err := yaml.Unmarshal(contents, &manifest); err != nil {
-		return yamlFile{}, fmt.Errorf("unable to parse YAML file number %d: %w", s.fileCount, err)
+		return yamlFile{}, fmt.Errorf("unable to parse YAML file number %d%s: %w", s.fileCount, at(s.source, s.line), err)
 	}
 
-	// Render the name to a buffer using the Go Template
-	s.log.Println("Rendering filename template from Go Template")
+	// Render the name to a buffer using the Go Template. When joining
+	// resources there are no files to name, so it's skipped
 	var buf bytes.Buffer
-	if err := s.template.Execute(&buf, manifest); err != nil {
-		return yamlFile{}, fmt.Errorf("unable to render file name for YAML file number %d: %w", s.fileCount, improveExecError(err))
+	if !s.opts.Join {
+		s.log.Println("Rendering filename template from Go Template")
+		if err := s.template.Execute(&buf, s.templateData(manifest)); err != nil {
+			return yamlFile{}, fmt.Errorf("unable to render file name for YAML file number %d%s: %w", s.fileCount, at(s.source, s.line), improveExecError(err))
+		}
 	}
 
 	// Check if file contains the required Kubernetes metadata
@@ -35,22 +39,88 @@ func (s *Split) parseYAMLManifest(contents []byte) (yamlFile, error) {
 	// Check if at least the three fields are not empty
 	if s.opts.StrictKubernetes {
 		if k8smeta.APIVersion == "" {
-			return yamlFile{}, &strictModeSkipErr{fieldName: "apiVersion"}
+			return yamlFile{}, &strictModeSkipErr{fieldName: "apiVersion", meta: k8smeta}
 		}
 
 		if k8smeta.Kind == "" {
-			return yamlFile{}, &strictModeSkipErr{fieldName: "kind"}
+			return yamlFile{}, &strictModeSkipErr{fieldName: "kind", meta: k8smeta}
 		}
 
 		if k8smeta.Name == "" {
-			return yamlFile{}, &strictModeSkipErr{fieldName: "metadata.name"}
+			return yamlFile{}, &strictModeSkipErr{fieldName: "metadata.name", meta: k8smeta}
+		}
+	}
+
+	// Apply all the filters to check if the resource should be skipped
+	included, err := s.applyFilters(manifest, k8smeta)
+	if err != nil {
+		return yamlFile{}, err
+	}
+
+	file := yamlFile{meta: k8smeta}
+
+	if !s.opts.Join {
+		name, err := s.fileName(buf.String(), k8smeta)
+		if err != nil {
+			return yamlFile{}, err
 		}
+
+		file.filename = name
 	}
 
+	// Keep the rules that included the file only when they're going
+	// to be shown to the user
+	if s.opts.Explain {
+		file.reasons = included
+	}
+
+	return file, nil
+}
+
+// fileName cleans up the file name rendered by the template and ensures
+// it can be used to write the resource to the output directory
+func (s *Split) fileName(rendered string, k8smeta kubeObjectMeta) (string, error) {
+	// Trim the file name
+	name := strings.TrimSpace(rendered)
+
+	// Fix for text/template Go issue #24963, as well as removing any linebreaks
+	name = strings.NewReplacer("<no value>", "", "\n", "").Replace(name)
+
+	if str := strings.TrimSuffix(name, filepath.Ext(name)); str == "" {
+		return "", fmt.Errorf("file name rendered will yield no file name for YAML file number %d%s (original name: %q, metadata: %v)", s.fileCount, at(s.source, s.line), name, k8smeta)
+	}
+
+	// Since the file name might come from untrusted manifests, ensure it
+	// can't be used to write files outside the output directory
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("file name %q rendered for YAML file number %d%s (kind %q, name %q) resolves outside the output directory", name, s.fileCount, at(s.source, s.line), k8smeta.Kind, k8smeta.Name)
+	}
+
+	// Clean the name so files rendered to equivalent paths, like
+	// "./foo.yaml" and "foo.yaml", are treated as the same file
+	name = filepath.Clean(name)
+
+	// Kustomization files are generated, so resources can't be written
+	// to them
+	if s.opts.Kustomize && inarray(filepath.Base(name), kustomizationNames) {
+		return "", fmt.Errorf("file name %q rendered for YAML file number %d%s (kind %q, name %q) is reserved for the kustomization files generated", name, s.fileCount, at(s.source, s.line), k8smeta.Kind, k8smeta.Name)
+	}
+
+	return name, nil
+}
+
+// applyFilters checks the resource against all the configured filters. If the
+// resource has to be skipped, a *skipErr is returned with the rule that caused
+// it; otherwise, the list of inclusion rules the resource matched is returned.
+// For every filter family, inclusions are applied first to narrow down the
+// resources, then exclusions remove resources from that narrowed set.
+func (s *Split) applyFilters(manifest map[string]interface{}, k8smeta kubeObjectMeta) ([]string, error) {
 	// Check before handling if we're about to filter resources
 	var (
-		hasIncluded = len(s.opts.Included) > 0
-		hasExcluded = len(s.opts.Excluded) > 0
+		hasIncluded = len(s.opts.Included) > 0 || len(s.includedRegex) > 0
+		hasExcluded = len(s.opts.Excluded) > 0 || len(s.excludedRegex) > 0
+		kindName    = fmt.Sprintf("%s/%s", k8smeta.Kind, k8smeta.Name)
+		included    []string
 	)
 
 	s.log.Printf("Applying filters -> Included: %v; Excluded: %v", s.opts.Included, s.opts.Excluded)
@@ -58,55 +128,126 @@ func (s *Split) parseYAMLManifest(contents []byte) (yamlFile, error) {
 
 	// Check if we have a Kubernetes kind and we're requesting inclusion or exclusion
 	if k8smeta.Kind == "" && !s.opts.AllowEmptyKinds && (hasIncluded || hasExcluded) {
-		return yamlFile{}, &cantFindFieldErr{fieldName: "kind", fileCount: s.fileCount, meta: k8smeta}
+		return nil, &cantFindFieldErr{fieldName: "kind", fileCount: s.fileCount, location: at(s.source, s.line), meta: k8smeta}
 	}
 
 	// Check if we have a Kubernetes name and we're requesting inclusion or exclusion
 	if k8smeta.Name == "" && !s.opts.AllowEmptyNames && (hasIncluded || hasExcluded) {
-		return yamlFile{}, &cantFindFieldErr{fieldName: "metadata.name", fileCount: s.fileCount, meta: k8smeta}
+		return nil, &cantFindFieldErr{fieldName: "metadata.name", fileCount: s.fileCount, location: at(s.source, s.line), meta: k8smeta}
+	}
+
+	// If we're working with including only specific resources, then filter by them
+	if hasIncluded {
+		if pattern, found := firstMatchIgnoreCaseGlob(s.opts.Included, kindName); found {
+			included = append(included, fmt.Sprintf("included by kind/name pattern %q", pattern))
+		} else if re, found := firstMatchRegex(s.includedRegex, kindName); found {
+			included = append(included, fmt.Sprintf("included by kind/name regex %q", unprefixRegex(re)))
+		} else {
+			return nil, &skipErr{meta: k8smeta, kind: "kind/name", name: kindName, reason: "not matched by any kind/name inclusion pattern"}
+		}
 	}
 
-	// We need to check if the file should be skipped
-	if hasExcluded || hasIncluded {
-		// If we're working with including only specific resources, then filter by them
-		if hasIncluded && !inSliceIgnoreCaseGlob(s.opts.Included, fmt.Sprintf("%s/%s", k8smeta.Kind, k8smeta.Name)) {
-			return yamlFile{}, &skipErr{kind: "kind/name", name: fmt.Sprintf("%s/%s", k8smeta.Kind, k8smeta.Name)}
+	// Then exclude resources from the included ones based on the parameter received
+	if hasExcluded {
+		if pattern, found := firstMatchIgnoreCaseGlob(s.opts.Excluded, kindName); found {
+			return nil, &skipErr{meta: k8smeta, kind: "kind/name", name: kindName, reason: fmt.Sprintf("excluded by kind/name pattern %q", pattern)}
 		}
 
-		// Otherwise exclude resources based on the parameter received
-		if hasExcluded && inSliceIgnoreCaseGlob(s.opts.Excluded, fmt.Sprintf("%s/%s", k8smeta.Kind, k8smeta.Name)) {
-			return yamlFile{}, &skipErr{kind: "kind/name", name: fmt.Sprintf("%s/%s", k8smeta.Kind, k8smeta.Name)}
+		if re, found := firstMatchRegex(s.excludedRegex, kindName); found {
+			return nil, &skipErr{meta: k8smeta, kind: "kind/name", name: kindName, reason: fmt.Sprintf("excluded by kind/name regex %q", unprefixRegex(re))}
 		}
 	}
 
 	if len(s.opts.IncludedGroups) > 0 || len(s.opts.ExcludedGroups) > 0 {
 		if k8smeta.APIVersion == "" {
-			return yamlFile{}, &cantFindFieldErr{fieldName: "apiVersion", fileCount: s.fileCount, meta: k8smeta}
+			return nil, &cantFindFieldErr{fieldName: "apiVersion", fileCount: s.fileCount, location: at(s.source, s.line), meta: k8smeta}
 		}
 
-		var groups []string
 		if len(s.opts.IncludedGroups) > 0 {
-			groups = s.opts.IncludedGroups
-		} else if len(s.opts.ExcludedGroups) > 0 {
-			groups = s.opts.ExcludedGroups
+			if err := checkGroup(k8smeta, s.opts.IncludedGroups, true); err != nil {
+				return nil, err
+			}
+
+			pattern, _ := firstMatchIgnoreCaseGlob(s.opts.IncludedGroups, groupName(k8smeta))
+			included = append(included, fmt.Sprintf("included by group pattern %q", pattern))
 		}
 
-		if err := checkGroup(k8smeta, groups, len(s.opts.IncludedGroups) > 0); err != nil {
-			return yamlFile{}, &skipErr{}
+		if len(s.opts.ExcludedGroups) > 0 {
+			if err := checkGroup(k8smeta, s.opts.ExcludedGroups, false); err != nil {
+				return nil, err
+			}
 		}
 	}
 
-	// Trim the file name
-	name := strings.TrimSpace(buf.String())
+	if len(s.opts.IncludedAPIVersions) > 0 || len(s.opts.ExcludedAPIVersions) > 0 {
+		if k8smeta.APIVersion == "" {
+			return nil, &cantFindFieldErr{fieldName: "apiVersion", fileCount: s.fileCount, location: at(s.source, s.line), meta: k8smeta}
+		}
 
-	// Fix for text/template Go issue #24963, as well as removing any linebreaks
-	name = strings.NewReplacer("<no value>", "", "\n", "").Replace(name)
+		if len(s.opts.IncludedAPIVersions) > 0 {
+			pattern, found := firstMatchAPIVersion(s.opts.IncludedAPIVersions, k8smeta)
+			if !found {
+				return nil, &skipErr{meta: k8smeta, kind: "apiVersion", name: k8smeta.APIVersion, reason: "not matched by any API version inclusion pattern"}
+			}
 
-	if str := strings.TrimSuffix(name, filepath.Ext(name)); str == "" {
-		return yamlFile{}, fmt.Errorf("file name rendered will yield no file name for YAML file number %d (original name: %q, metadata: %v)", s.fileCount, name, k8smeta)
+			included = append(included, fmt.Sprintf("included by API version pattern %q", pattern))
+		}
+
+		if pattern, found := firstMatchAPIVersion(s.opts.ExcludedAPIVersions, k8smeta); found {
+			return nil, &skipErr{meta: k8smeta, kind: "apiVersion", name: k8smeta.APIVersion, reason: fmt.Sprintf("excluded by API version pattern %q", pattern)}
+		}
 	}
 
-	return yamlFile{filename: name, meta: k8smeta}, nil
+	if len(s.opts.IncludedNamespaces) > 0 || len(s.opts.ExcludedNamespaces) > 0 {
+		namespace := k8smeta.Namespace
+		if namespace == "" {
+			namespace = emptyNamespace
+		}
+
+		if len(s.opts.IncludedNamespaces) > 0 {
+			pattern, found := firstMatchIgnoreCaseGlob(s.opts.IncludedNamespaces, namespace)
+			if !found {
+				return nil, &skipErr{meta: k8smeta, kind: "namespace", name: namespace, reason: "not matched by any namespace inclusion pattern"}
+			}
+
+			included = append(included, fmt.Sprintf("included by namespace pattern %q", pattern))
+		}
+
+		if pattern, found := firstMatchIgnoreCaseGlob(s.opts.ExcludedNamespaces, namespace); found {
+			return nil, &skipErr{meta: k8smeta, kind: "namespace", name: namespace, reason: fmt.Sprintf("excluded by namespace pattern %q", pattern)}
+		}
+	}
+
+	if s.labelSelector != nil {
+		if req, found := s.labelSelector.firstUnmatched(k8smeta.Labels); found {
+			return nil, &skipErr{meta: k8smeta, kind: "label selector", name: s.labelSelector.String(), reason: fmt.Sprintf("label selector requirement %q not satisfied", req)}
+		}
+
+		included = append(included, fmt.Sprintf("label selector %q satisfied", s.labelSelector))
+	}
+
+	if len(s.opts.IncludedAnnotations) > 0 {
+		pattern, found := firstMatchAnnotation(s.opts.IncludedAnnotations, k8smeta.Annotations)
+		if !found {
+			return nil, &skipErr{meta: k8smeta, kind: "annotation", name: strings.Join(s.opts.IncludedAnnotations, ","), reason: "not matched by any annotation inclusion pattern"}
+		}
+
+		included = append(included, fmt.Sprintf("included by annotation pattern %q", pattern))
+	}
+
+	if pattern, found := firstMatchAnnotation(s.opts.ExcludedAnnotations, k8smeta.Annotations); found {
+		return nil, &skipErr{meta: k8smeta, kind: "annotation", name: pattern, reason: fmt.Sprintf("excluded by annotation pattern %q", pattern)}
+	}
+
+	for _, expr := range s.where {
+		if !expr.matches(manifest) {
+			return nil, &skipErr{meta: k8smeta, kind: "where expression", name: expr.String(), reason: fmt.Sprintf("where expression %q not satisfied", expr)}
+		}
+
+		included = append(included, fmt.Sprintf("where expression %q satisfied", expr))
+	}
+
+	return included, nil
 }
 
 // inSliceIgnoreCase checks if a string is in a slice, ignoring case
@@ -125,17 +266,61 @@ func inSliceIgnoreCase(slice []string, expected string) bool {
 // inSliceIgnoreCaseGlob checks if a string is in a slice, ignoring case and
 // allowing the use of a glob pattern
 func inSliceIgnoreCaseGlob(slice []string, expected string) bool {
+	_, found := firstMatchIgnoreCaseGlob(slice, expected)
+	return found
+}
+
+// firstMatchIgnoreCaseGlob returns the first pattern in the slice matching the
+// string, ignoring case and allowing the use of a glob pattern
+func firstMatchIgnoreCaseGlob(slice []string, expected string) (string, bool) {
 	expected = strings.ToLower(expected)
 
 	for _, pattern := range slice {
-		pattern = strings.ToLower(pattern)
+		if match, _ := glob.Match(strings.ToLower(pattern), expected); match {
+			return pattern, true
+		}
+	}
 
-		if match, _ := glob.Match(pattern, expected); match {
-			return true
+	return "", false
+}
+
+// firstMatchRegex returns the first regular expression matching the string
+func firstMatchRegex(list []*regexp.Regexp, expected string) (*regexp.Regexp, bool) {
+	for _, re := range list {
+		if re.MatchString(expected) {
+			return re, true
 		}
 	}
 
-	return false
+	return nil, false
+}
+
+// unprefixRegex returns the regular expression as provided by the user,
+// without the case insensitive flag added when compiling it
+func unprefixRegex(re *regexp.Regexp) string {
+	return strings.TrimPrefix(re.String(), "(?i)")
+}
+
+// firstMatchAnnotation returns the first pattern matching any of the
+// annotations. Patterns use the format "key" to match by key only, or
+// "key=value" to match both key and value, case insensitive and with glob
+// support on both
+func firstMatchAnnotation(patterns []string, annotations map[string]string) (string, bool) {
+	for _, pattern := range patterns {
+		key, value, hasValue := strings.Cut(pattern, "=")
+
+		for k, v := range annotations {
+			if !inSliceIgnoreCaseGlob([]string{key}, k) {
+				continue
+			}
+
+			if !hasValue || inSliceIgnoreCaseGlob([]string{value}, v) {
+				return pattern, true
+			}
+		}
+	}
+
+	return "", false
 }
 
 // checkStringInMap checks if a string is in a map, and if not, returns an error
@@ -161,31 +346,86 @@ func checkKubernetesBasics(manifest map[string]interface{}) kubeObjectMeta {
 	metadata.APIVersion = checkStringInMap(manifest, "apiVersion")
 	metadata.Kind = checkStringInMap(manifest, "kind")
 
-	if md, found := manifest["metadata"]; found {
-		metadata.Name = checkStringInMap(md.(map[string]interface{}), "name")
-		metadata.Namespace = checkStringInMap(md.(map[string]interface{}), "namespace")
+	// Metadata that isn't a map, like "metadata: foo", is handled as if
+	// it were empty
+	if md, ok := manifest["metadata"].(map[string]interface{}); ok {
+		metadata.Name = checkStringInMap(md, "name")
+		metadata.Namespace = checkStringInMap(md, "namespace")
+		metadata.Labels = checkStringMapInMap(md, "labels")
+		metadata.Annotations = checkStringMapInMap(md, "annotations")
 	}
 
 	return metadata
 }
 
-func checkGroup(objmeta kubeObjectMeta, groupName []string, included bool) error {
+// checkStringMapInMap retrieves a map of strings from a map, like labels or
+// annotations, converting any non-string values to their string representation
+func checkStringMapInMap(local map[string]interface{}, key string) map[string]string {
+	iface, found := local[key]
+	if !found {
+		return nil
+	}
 
-	for _, group := range groupName {
-		if included {
-			if objmeta.GetGroupFromAPIVersion() == strings.ToLower(group) {
-				return nil
-			}
-		} else {
-			if objmeta.GetGroupFromAPIVersion() == strings.ToLower(group) {
-				return &skipErr{}
-			}
+	m, ok := iface.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make(map[string]string, len(m))
+	for k, v := range m {
+		if v == nil {
+			result[k] = ""
+			continue
 		}
+
+		result[k] = fmt.Sprintf("%v", v)
 	}
 
-	if included {
-		return &skipErr{}
-	} else {
-		return nil
+	return result
+}
+
+// checkGroup checks the resource group against the group patterns, returning a
+// skipErr if the resource should be skipped; resources from the core group are
+// matched using the "core" name
+func checkGroup(objmeta kubeObjectMeta, groups []string, included bool) error {
+	group := groupName(objmeta)
+	pattern, found := firstMatchIgnoreCaseGlob(groups, group)
+
+	if included && !found {
+		return &skipErr{meta: objmeta, kind: "group", name: group, reason: "not matched by any group inclusion pattern"}
 	}
+
+	if !included && found {
+		return &skipErr{meta: objmeta, kind: "group", name: group, reason: fmt.Sprintf("excluded by group pattern %q", pattern)}
+	}
+
+	return nil
+}
+
+// groupName returns the resource API group, or "core" for the core group
+func groupName(objmeta kubeObjectMeta) string {
+	if group := objmeta.GetGroupFromAPIVersion(); group != "" {
+		return group
+	}
+
+	return coreGroup
+}
+
+// firstMatchAPIVersion returns the first pattern matching the resource
+// apiVersion. Patterns containing a slash are matched against the full
+// apiVersion, like "apps/v1", while patterns without one are matched against
+// the version only, like "v1beta1"
+func firstMatchAPIVersion(patterns []string, objmeta kubeObjectMeta) (string, bool) {
+	for _, pattern := range patterns {
+		value := objmeta.GetVersionFromAPIVersion()
+		if strings.Contains(pattern, "/") {
+			value = objmeta.APIVersion
+		}
+
+		if inSliceIgnoreCaseGlob([]string{pattern}, value) {
+			return pattern, true
+		}
+	}
+
+	return "", false
 }
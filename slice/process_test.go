@@ -126,6 +126,148 @@ func Test_inSliceIgnoreCaseGlob(t *testing.T) {
 	}
 }
 
+func Test_firstMatchAnnotation(t *testing.T) {
+	annotations := map[string]string{
+		"helm.sh/hook":                 "pre-install,pre-upgrade",
+		"argocd.argoproj.io/sync-wave": "5",
+	}
+
+	tests := []struct {
+		name        string
+		patterns    []string
+		annotations map[string]string
+		want        bool
+	}{
+		{
+			name:        "key only",
+			patterns:    []string{"argocd.argoproj.io/sync-wave"},
+			annotations: annotations,
+			want:        true,
+		},
+		{
+			name:        "key only, case insensitive",
+			patterns:    []string{"Helm.sh/Hook"},
+			annotations: annotations,
+			want:        true,
+		},
+		{
+			name:        "key glob",
+			patterns:    []string{"argocd.argoproj.io/*"},
+			annotations: annotations,
+			want:        true,
+		},
+		{
+			name:        "key and any value",
+			patterns:    []string{"helm.sh/hook=*"},
+			annotations: annotations,
+			want:        true,
+		},
+		{
+			name:        "key and value glob",
+			patterns:    []string{"helm.sh/hook=pre-install*"},
+			annotations: annotations,
+			want:        true,
+		},
+		{
+			name:        "key matching but value not matching",
+			patterns:    []string{"helm.sh/hook=test"},
+			annotations: annotations,
+			want:        false,
+		},
+		{
+			name:        "key and empty value",
+			patterns:    []string{"helm.sh/hook="},
+			annotations: map[string]string{"helm.sh/hook": ""},
+			want:        true,
+		},
+		{
+			name:        "second pattern matches",
+			patterns:    []string{"foo", "argocd.argoproj.io/sync-wave=5"},
+			annotations: annotations,
+			want:        true,
+		},
+		{
+			name:        "no pattern matches",
+			patterns:    []string{"foo", "bar=baz"},
+			annotations: annotations,
+			want:        false,
+		},
+		{
+			name:        "no annotations",
+			patterns:    []string{"helm.sh/hook"},
+			annotations: nil,
+			want:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, found := firstMatchAnnotation(tt.patterns, tt.annotations)
+			require.Equal(t, tt.want, found)
+		})
+	}
+}
+
+func Test_checkGroup(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		groups     []string
+		included   bool
+		wantSkip   bool
+	}{
+		{name: "included exact group", apiVersion: "apps/v1", groups: []string{"apps"}, included: true},
+		{name: "included group case insensitive", apiVersion: "apps/v1", groups: []string{"Apps"}, included: true},
+		{name: "included group mismatch", apiVersion: "batch/v1", groups: []string{"apps"}, included: true, wantSkip: true},
+		{name: "included group glob", apiVersion: "rbac.authorization.k8s.io/v1", groups: []string{"*.k8s.io"}, included: true},
+		{name: "included group glob mismatch", apiVersion: "cert-manager.io/v1", groups: []string{"*.k8s.io"}, included: true, wantSkip: true},
+		{name: "included core group", apiVersion: "v1", groups: []string{"core"}, included: true},
+		{name: "core group not matched by other globs", apiVersion: "v1", groups: []string{"*.k8s.io"}, included: true, wantSkip: true},
+		{name: "excluded group", apiVersion: "batch/v1", groups: []string{"batch"}, included: false, wantSkip: true},
+		{name: "excluded group mismatch", apiVersion: "apps/v1", groups: []string{"batch"}, included: false},
+		{name: "excluded group glob", apiVersion: "networking.k8s.io/v1", groups: []string{"*.k8s.io"}, included: false, wantSkip: true},
+		{name: "excluded core group", apiVersion: "v1", groups: []string{"CORE"}, included: false, wantSkip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkGroup(kubeObjectMeta{APIVersion: tt.apiVersion}, tt.groups, tt.included)
+			requireErrorIf(t, tt.wantSkip, err)
+		})
+	}
+}
+
+func Test_firstMatchAPIVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiVersion string
+		patterns   []string
+		want       bool
+	}{
+		{name: "version only", apiVersion: "batch/v1beta1", patterns: []string{"v1beta1"}, want: true},
+		{name: "version only, core group", apiVersion: "v1", patterns: []string{"v1"}, want: true},
+		{name: "version only mismatch", apiVersion: "batch/v1", patterns: []string{"v1beta1"}, want: false},
+		{name: "version glob", apiVersion: "policy/v1beta1", patterns: []string{"*beta*"}, want: true},
+		{name: "version glob mismatch", apiVersion: "policy/v1", patterns: []string{"*beta*", "*alpha*"}, want: false},
+		{name: "full apiVersion", apiVersion: "apps/v1", patterns: []string{"apps/v1"}, want: true},
+		{name: "full apiVersion case insensitive", apiVersion: "apps/v1", patterns: []string{"Apps/V1"}, want: true},
+		{name: "full apiVersion mismatch", apiVersion: "batch/v1", patterns: []string{"apps/v1"}, want: false},
+		{name: "full apiVersion glob", apiVersion: "networking.k8s.io/v1beta1", patterns: []string{"*.k8s.io/v1beta1"}, want: true},
+		{name: "full apiVersion glob doesn't match core group", apiVersion: "v1", patterns: []string{"*/v1"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, found := firstMatchAPIVersion(tt.patterns, kubeObjectMeta{APIVersion: tt.apiVersion})
+			require.Equal(t, tt.want, found)
+		})
+	}
+}
+
 func Test_checkStringInMap(t *testing.T) {
 	type args struct {
 		local map[string]interface{}
@@ -206,6 +348,28 @@ func Test_checkKubernetesBasics(t *testing.T) {
 				Name:       "foo",
 			},
 		},
+		{
+			name: "labels found",
+			args: args{
+				manifest: map[string]interface{}{
+					"kind":       "Deployment",
+					"apiVersion": "apps/v1",
+					"metadata": map[string]interface{}{
+						"name": "foo",
+						"labels": map[string]interface{}{
+							"app":     "foo",
+							"version": 2,
+						},
+					},
+				},
+			},
+			want: kubeObjectMeta{
+				Kind:       "Deployment",
+				APIVersion: "apps/v1",
+				Name:       "foo",
+				Labels:     map[string]string{"app": "foo", "version": "2"},
+			},
+		},
 		{
 			name: "no fields found",
 			args: args{
@@ -355,6 +519,43 @@ kind: Foo
 	}
 }
 
+func TestSplit_parseYAMLManifestOutsideOutputDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		resource string
+		want     string
+		wantErr  bool
+	}{
+		{name: "plain name", resource: "foo", want: "foo.yaml"},
+		{name: "subfolder", resource: "bar/foo", want: "bar/foo.yaml"},
+		{name: "parent folder inside output dir", resource: "bar/../foo", want: "foo.yaml"},
+		{name: "current folder", resource: "./foo", want: "foo.yaml"},
+		{name: "parent folder", resource: "../foo", wantErr: true},
+		{name: "nested parent folders", resource: "bar/../../../etc/foo", wantErr: true},
+		{name: "absolute path", resource: "/etc/foo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Split{
+				log:      nolog,
+				template: template.Must(template.New(DefaultTemplateName).Funcs(local.Functions).Parse("{{.metadata.name}}.yaml")),
+			}
+
+			got, err := s.parseYAMLManifest([]byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: " + tt.resource))
+			requireErrorIf(t, tt.wantErr, err)
+			require.Equal(t, tt.want, got.filename)
+
+			if tt.wantErr {
+				require.ErrorContains(t, err, "resolves outside the output directory")
+				require.ErrorContains(t, err, tt.resource)
+			}
+		})
+	}
+}
+
 func TestSplit_parseYamlManifestAllowingEmpties(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -470,3 +671,301 @@ kind: Foo
 		})
 	}
 }
+
+func TestSplit_combinedIncludeExcludeFilters(t *testing.T) {
+	resources := []string{
+		`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+  labels:
+    app: web
+`,
+		`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web-canary
+  namespace: prod
+  labels:
+    app: web
+    track: canary
+`,
+		`apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+  namespace: kube-system
+`,
+		`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: prod
+  labels:
+    app: web
+`,
+		`apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+  annotations:
+    helm.sh/hook: pre-install
+`,
+		`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+  namespace: prod
+  annotations:
+    helm.sh/hook: pre-upgrade
+`,
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{
+			name: "no filters",
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml", "daemonset-agent.yaml", "service-web.yaml", "clusterrole-reader.yaml", "job-migrate.yaml"},
+		},
+		{
+			name: "include kind, exclude name",
+			opts: Options{IncludedKinds: []string{"Deployment"}, ExcludedNames: []string{"*-canary"}},
+			want: []string{"deployment-web.yaml"},
+		},
+		{
+			name: "include name, exclude kind",
+			opts: Options{IncludedNames: []string{"web*"}, ExcludedKinds: []string{"Service"}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml"},
+		},
+		{
+			name: "include and exclude the same kind",
+			opts: Options{IncludedKinds: []string{"Deployment"}, ExcludedKinds: []string{"deployment"}},
+			want: nil,
+		},
+		{
+			name: "include and exclude kinds",
+			opts: Options{IncludedKinds: []string{"Deployment", "DaemonSet"}, ExcludedKinds: []string{"DaemonSet"}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml"},
+		},
+		{
+			name: "include and exclude names",
+			opts: Options{IncludedNames: []string{"web*"}, ExcludedNames: []string{"*-canary"}},
+			want: []string{"deployment-web.yaml", "service-web.yaml"},
+		},
+		{
+			name: "include and exclude kind/name",
+			opts: Options{Included: []string{"deployment/*"}, Excluded: []string{"*/web-canary"}},
+			want: []string{"deployment-web.yaml"},
+		},
+		{
+			name: "includes within the kind/name family are combined",
+			opts: Options{IncludedKinds: []string{"Service"}, IncludedNames: []string{"agent"}},
+			want: []string{"daemonset-agent.yaml", "service-web.yaml"},
+		},
+		{
+			name: "include regex",
+			opts: Options{IncludedRegex: []string{`^deployment/web(-canary)?$`, `^(service|job)/`}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml", "service-web.yaml", "job-migrate.yaml"},
+		},
+		{
+			name: "include regex is case insensitive",
+			opts: Options{IncludedRegex: []string{`^DEPLOYMENT/WEB$`}},
+			want: []string{"deployment-web.yaml"},
+		},
+		{
+			name: "exclude regex",
+			opts: Options{ExcludedRegex: []string{`^[a-z]+/(web|agent)$`}},
+			want: []string{"deployment-web-canary.yaml", "clusterrole-reader.yaml", "job-migrate.yaml"},
+		},
+		{
+			name: "include and exclude regex",
+			opts: Options{IncludedRegex: []string{`^deployment/`}, ExcludedRegex: []string{`-canary$`}},
+			want: []string{"deployment-web.yaml"},
+		},
+		{
+			name: "include regex combined with include glob",
+			opts: Options{IncludedRegex: []string{`^service/`}, IncludedKinds: []string{"DaemonSet"}},
+			want: []string{"daemonset-agent.yaml", "service-web.yaml"},
+		},
+		{
+			name: "include glob, exclude regex",
+			opts: Options{IncludedNames: []string{"web*"}, ExcludedRegex: []string{`^service/`, `canary`}},
+			want: []string{"deployment-web.yaml"},
+		},
+		{
+			name: "include group, exclude kind",
+			opts: Options{IncludedGroups: []string{"apps"}, ExcludedKinds: []string{"DaemonSet"}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml"},
+		},
+		{
+			name: "include and exclude groups",
+			opts: Options{IncludedGroups: []string{"apps", "batch"}, ExcludedGroups: []string{"batch"}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml", "daemonset-agent.yaml"},
+		},
+		{
+			name: "include and exclude the same group",
+			opts: Options{IncludedGroups: []string{"apps"}, ExcludedGroups: []string{"apps"}},
+			want: nil,
+		},
+		{
+			name: "exclude group, include kind",
+			opts: Options{IncludedKinds: []string{"Deployment", "Job"}, ExcludedGroups: []string{"batch"}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml"},
+		},
+		{
+			name: "include and exclude namespaces",
+			opts: Options{IncludedNamespaces: []string{"*"}, ExcludedNamespaces: []string{"<none>", "kube-*"}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml", "service-web.yaml", "job-migrate.yaml"},
+		},
+		{
+			name: "include core group",
+			opts: Options{IncludedGroups: []string{"core"}},
+			want: []string{"service-web.yaml"},
+		},
+		{
+			name: "include group glob, exclude api version",
+			opts: Options{IncludedGroups: []string{"*.k8s.io", "batch"}, ExcludedAPIVersions: []string{"batch/*"}},
+			want: []string{"clusterrole-reader.yaml"},
+		},
+		{
+			name: "include and exclude api versions",
+			opts: Options{IncludedAPIVersions: []string{"v1"}, ExcludedAPIVersions: []string{"apps/*"}},
+			want: []string{"service-web.yaml", "clusterrole-reader.yaml", "job-migrate.yaml"},
+		},
+		{
+			name: "include namespace, exclude kind",
+			opts: Options{IncludedNamespaces: []string{"prod"}, ExcludedKinds: []string{"Deployment"}},
+			want: []string{"service-web.yaml", "job-migrate.yaml"},
+		},
+		{
+			name: "include and exclude annotations",
+			opts: Options{IncludedAnnotations: []string{"helm.sh/hook"}, ExcludedAnnotations: []string{"helm.sh/hook=pre-install"}},
+			want: []string{"job-migrate.yaml"},
+		},
+		{
+			name: "include kind, exclude annotation",
+			opts: Options{IncludedKinds: []string{"Job", "Service"}, ExcludedAnnotations: []string{"helm.sh/hook=*"}},
+			want: []string{"service-web.yaml"},
+		},
+		{
+			name: "exclusions from multiple families",
+			opts: Options{ExcludedKinds: []string{"Service"}, ExcludedNamespaces: []string{"kube-system"}, ExcludedAnnotations: []string{"helm.sh/hook"}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml"},
+		},
+		{
+			name: "inclusions from multiple families",
+			opts: Options{IncludedGroups: []string{"apps", "batch"}, IncludedNamespaces: []string{"prod"}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml", "job-migrate.yaml"},
+		},
+		{
+			name: "label selector with exclusions",
+			opts: Options{LabelSelector: "app=web", ExcludedKinds: []string{"Service"}},
+			want: []string{"deployment-web.yaml", "deployment-web-canary.yaml"},
+		},
+		{
+			name: "where expression with exclusions",
+			opts: Options{Where: []string{`metadata.namespace == "prod"`}, ExcludedNames: []string{"web*"}},
+			want: []string{"job-migrate.yaml"},
+		},
+		{
+			name: "every family at once",
+			opts: Options{
+				IncludedKinds:       []string{"Deployment", "Job", "Service"},
+				ExcludedNames:       []string{"migrate"},
+				IncludedGroups:      []string{"apps", "batch"},
+				ExcludedGroups:      []string{"batch"},
+				IncludedNamespaces:  []string{"prod"},
+				ExcludedNamespaces:  []string{"kube-*"},
+				ExcludedAnnotations: []string{"helm.sh/hook"},
+				LabelSelector:       "track!=canary",
+				Where:               []string{"has(metadata.labels)"},
+			},
+			want: []string{"deployment-web.yaml"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Split{
+				opts:     tt.opts,
+				log:      nolog,
+				template: template.Must(template.New(DefaultTemplateName).Funcs(local.Functions).Parse(DefaultTemplateName)),
+			}
+
+			require.NoError(t, s.validateFilters())
+
+			for _, resource := range resources {
+				require.NoError(t, s.processSingleFile([]byte(resource)))
+			}
+
+			var got []string
+			for _, v := range s.filesFound {
+				got = append(got, v.filename)
+			}
+
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplit_parseYAMLManifestSkipErrors(t *testing.T) {
+	manifest := []byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  labels:
+    app: web
+  annotations:
+    helm.sh/hook: pre-install
+`)
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{
+			name:    "label selector",
+			opts:    Options{LabelSelector: "app=api"},
+			wantErr: `resource label selector "app=api" is configured to be skipped: label selector requirement "app=api" not satisfied`,
+		},
+		{
+			name:    "annotation inclusion",
+			opts:    Options{IncludedAnnotations: []string{"team", "owner"}},
+			wantErr: `resource annotation "team,owner" is configured to be skipped: not matched by any annotation inclusion pattern`,
+		},
+		{
+			name:    "annotation exclusion",
+			opts:    Options{ExcludedAnnotations: []string{"helm.sh/hook=pre-*"}},
+			wantErr: `resource annotation "helm.sh/hook=pre-*" is configured to be skipped: excluded by annotation pattern "helm.sh/hook=pre-*"`,
+		},
+		{
+			name:    "where expression",
+			opts:    Options{Where: []string{`kind == "Pod"`}},
+			wantErr: `resource where expression "kind == \"Pod\"" is configured to be skipped: where expression "kind == \"Pod\"" not satisfied`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Split{
+				opts:     tt.opts,
+				log:      nolog,
+				template: template.Must(template.New(DefaultTemplateName).Funcs(local.Functions).Parse(DefaultTemplateName)),
+			}
+
+			require.NoError(t, s.validateFilters())
+
+			_, err := s.parseYAMLManifest(manifest)
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package slice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+)
+
+// reportDocument is a single document found in the input
+type reportDocument struct {
+	Index      int    `json:"index"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	APIVersion string `json:"apiVersion"`
+}
+
+// reportFile is a file generated from one or more documents
+type reportFile struct {
+	Path      string           `json:"path"`
+	Size      int              `json:"size"`
+	SHA256    string           `json:"sha256"`
+	Documents []reportDocument `json:"documents"`
+}
+
+// reportSkipped is a document that was not written to any file
+type reportSkipped struct {
+	reportDocument
+	Reason string `json:"reason"`
+}
+
+// report is the machine-readable summary of a run
+type report struct {
+	OutputDirectory string          `json:"outputDirectory,omitempty"`
+	OutputArchive   string          `json:"outputArchive,omitempty"`
+	DryRun          bool            `json:"dryRun"`
+	Stdout          bool            `json:"stdout"`
+	Files           []reportFile    `json:"files"`
+	Skipped         []reportSkipped `json:"skipped"`
+}
+
+// sourceDocument is a document from the input merged into a file
+type sourceDocument struct {
+	index  int
+	source string
+	line   int
+	meta   kubeObjectMeta
+
+	// The position of the document contents in the data of the file it
+	// was merged into
+	start, end int
+}
+
+// skippedDocument is a document from the input that was skipped
+type skippedDocument struct {
+	sourceDocument
+	reason string
+}
+
+func (d sourceDocument) toReport() reportDocument {
+	return reportDocument{
+		Index:      d.index,
+		Kind:       d.meta.Kind,
+		Name:       d.meta.Name,
+		Namespace:  d.meta.Namespace,
+		APIVersion: d.meta.APIVersion,
+	}
+}
+
+// buildReport generates the report from the files found and skipped
+// during the scan
+func (s *Split) buildReport() report {
+	r := report{
+		DryRun:  s.opts.DryRun,
+		Stdout:  s.opts.OutputToStdout,
+		Files:   make([]reportFile, 0, len(s.filesFound)),
+		Skipped: make([]reportSkipped, 0, len(s.skipped)),
+	}
+
+	switch {
+	case s.opts.Sink != nil:
+		// Files written to a sink have no location on disk
+
+	case s.opts.OutputArchive != "":
+		r.OutputArchive = s.opts.OutputArchive
+
+	case !s.opts.OutputToStdout:
+		r.OutputDirectory = s.opts.OutputDirectory
+	}
+
+	for _, v := range s.filesFound {
+		// When streaming, the contents were already written and only
+		// their size and checksum were kept
+		size, sum := v.size, []byte(nil)
+		if v.digest != nil {
+			sum = v.digest.Sum(nil)
+		} else {
+			contents := s.fileContents(v)
+			digest := sha256.Sum256(contents)
+			size, sum = len(contents), digest[:]
+		}
+
+		file := reportFile{
+			Path:      filepath.Join(s.opts.OutputDirectory, v.filename),
+			Size:      size,
+			SHA256:    hex.EncodeToString(sum),
+			Documents: make([]reportDocument, 0, len(v.documents)),
+		}
+
+		for _, doc := range v.documents {
+			file.Documents = append(file.Documents, doc.toReport())
+		}
+
+		r.Files = append(r.Files, file)
+	}
+
+	for _, v := range s.skipped {
+		r.Skipped = append(r.Skipped, reportSkipped{
+			reportDocument: v.toReport(),
+			Reason:         v.reason,
+		})
+	}
+
+	return r
+}
+
+// writeReport writes the JSON report to the location requested by the
+// user, if any: "-" prints it to stdout, otherwise it's written to a file
+func (s *Split) writeReport() error {
+	if s.opts.Report == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.buildReport(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to generate report: %w", err)
+	}
+
+	if s.opts.Report == "-" {
+		s.WriteStdout("%s", data)
+		return nil
+	}
+
+	s.log.Printf("Writing report to %q", s.opts.Report)
+	return s.writeToFile(s.opts.Report, data)
+}
@@ -0,0 +1,105 @@
+package slice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecute_report(t *testing.T) {
+	input := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: prod
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: migrate
+`
+
+	tdinput := t.TempDir()
+	tdoutput := t.TempDir()
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+	s, err := New(Options{
+		GoTemplate:      "{{.metadata.name}}.yaml",
+		InputFile:       filepath.Join(tdinput, "input.yaml"),
+		OutputDirectory: tdoutput,
+		ExcludedKinds:   []string{"Job"},
+		Report:          reportPath,
+		Stderr:          io.Discard,
+		Stdout:          io.Discard,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Execute())
+
+	data, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+
+	var got report
+	require.NoError(t, json.Unmarshal(data, &got))
+
+	written, err := os.ReadFile(filepath.Join(tdoutput, "web.yaml"))
+	require.NoError(t, err)
+	sum := sha256.Sum256(written)
+
+	require.Equal(t, report{
+		OutputDirectory: tdoutput,
+		Files: []reportFile{
+			{
+				Path:   filepath.Join(tdoutput, "web.yaml"),
+				Size:   len(written),
+				SHA256: hex.EncodeToString(sum[:]),
+				Documents: []reportDocument{
+					{Index: 0, Kind: "Deployment", Name: "web", Namespace: "prod", APIVersion: "apps/v1"},
+					{Index: 1, Kind: "Service", Name: "web", Namespace: "prod", APIVersion: "v1"},
+				},
+			},
+		},
+		Skipped: []reportSkipped{
+			{
+				reportDocument: reportDocument{Index: 2, Kind: "Job", Name: "migrate", APIVersion: "batch/v1"},
+				Reason:         `excluded by kind/name pattern "Job/*"`,
+			},
+		},
+	}, got)
+}
+
+func TestSplit_fileContents(t *testing.T) {
+	tests := []struct {
+		name       string
+		tripleDash bool
+		data       string
+		want       string
+	}{
+		{name: "adds trailing newline", data: "kind: Pod", want: "kind: Pod\n"},
+		{name: "keeps trailing newline", data: "kind: Pod\n", want: "kind: Pod\n"},
+		{name: "includes triple dash", tripleDash: true, data: "kind: Pod", want: "---\nkind: Pod\n"},
+		{name: "triple dash only file", tripleDash: true, data: "---", want: "---\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &Split{opts: Options{IncludeTripleDash: tt.tripleDash}}
+			require.Equal(t, tt.want, string(s.fileContents(yamlFile{data: []byte(tt.data)})))
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package slice
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+type selectorOperator string
+
+const (
+	selectorEquals       selectorOperator = "="
+	selectorNotEquals    selectorOperator = "!="
+	selectorIn           selectorOperator = "in"
+	selectorNotIn        selectorOperator = "notin"
+	selectorExists       selectorOperator = "exists"
+	selectorDoesNotExist selectorOperator = "!"
+)
+
+var (
+	regSelectorEquality = regexp.MustCompile(`^([^\s=!(),]+)\s*(==|=|!=)\s*([^\s=!(),]*)$`)
+	regSelectorSet      = regexp.MustCompile(`^([^\s=!(),]+)\s+(in|notin)\s*\(([^()]*)\)$`)
+	regSelectorExists   = regexp.MustCompile(`^(!?)\s*([^\s=!(),]+)$`)
+
+	// from: https://kubernetes.io/docs/concepts/overview/working-with-objects/labels/#syntax-and-character-set
+	regLabelKey   = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	regLabelValue = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+)
+
+// labelRequirement is a single requirement of a label selector, such as
+// "app=web", "tier in (frontend,backend)" or "!legacy"
+type labelRequirement struct {
+	key      string
+	operator selectorOperator
+	values   []string
+}
+
+// labelSelector is a list of requirements that must all be satisfied by
+// the labels of a resource, following the same syntax used by "kubectl -l"
+type labelSelector []labelRequirement
+
+// parseLabelSelector parses a Kubernetes label selector as used by
+// "kubectl get -l": a comma-separated list of requirements using equality
+// ("=", "==", "!="), set ("in", "notin") or existence ("key", "!key")
+// operators
+func parseLabelSelector(selector string) (labelSelector, error) {
+	var requirements labelSelector
+
+	for _, raw := range splitSelector(selector) {
+		raw = strings.TrimSpace(raw)
+
+		if raw == "" {
+			return nil, fmt.Errorf("invalid label selector %q: found empty requirement", selector)
+		}
+
+		req, err := parseLabelRequirement(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid label selector %q: %w", selector, err)
+		}
+
+		if !regLabelKey.MatchString(req.key) {
+			return nil, fmt.Errorf("invalid label selector %q: invalid label key %q", selector, req.key)
+		}
+
+		for _, v := range req.values {
+			if !regLabelValue.MatchString(v) {
+				return nil, fmt.Errorf("invalid label selector %q: invalid label value %q", selector, v)
+			}
+		}
+
+		requirements = append(requirements, req)
+	}
+
+	return requirements, nil
+}
+
+// splitSelector splits a label selector by commas, ignoring the commas
+// used inside parenthesis for the set-based requirements
+func splitSelector(selector string) []string {
+	var (
+		parts []string
+		depth int
+		last  int
+	)
+
+	for pos, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, selector[last:pos])
+				last = pos + 1
+			}
+		}
+	}
+
+	return append(parts, selector[last:])
+}
+
+func parseLabelRequirement(raw string) (labelRequirement, error) {
+	if m := regSelectorSet.FindStringSubmatch(raw); m != nil {
+		var values []string
+		for _, v := range strings.Split(m[3], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+
+		if len(values) == 0 {
+			return labelRequirement{}, fmt.Errorf("requirement %q must have at least one value", raw)
+		}
+
+		return labelRequirement{key: m[1], operator: selectorOperator(m[2]), values: values}, nil
+	}
+
+	if m := regSelectorEquality.FindStringSubmatch(raw); m != nil {
+		op := selectorEquals
+		if m[2] == "!=" {
+			op = selectorNotEquals
+		}
+
+		return labelRequirement{key: m[1], operator: op, values: []string{m[3]}}, nil
+	}
+
+	if m := regSelectorExists.FindStringSubmatch(raw); m != nil {
+		if m[1] == "!" {
+			return labelRequirement{key: m[2], operator: selectorDoesNotExist}, nil
+		}
+
+		return labelRequirement{key: m[2], operator: selectorExists}, nil
+	}
+
+	return labelRequirement{}, fmt.Errorf("unable to parse requirement %q", raw)
+}
+
+// matches checks if the given labels satisfy the requirement
+func (r labelRequirement) matches(labels map[string]string) bool {
+	value, found := labels[r.key]
+
+	switch r.operator {
+	case selectorExists:
+		return found
+
+	case selectorDoesNotExist:
+		return !found
+
+	case selectorEquals, selectorIn:
+		return found && inarray(value, r.values)
+
+	case selectorNotEquals, selectorNotIn:
+		return !found || !inarray(value, r.values)
+	}
+
+	return false
+}
+
+// String returns the requirement using the label selector syntax
+func (r labelRequirement) String() string {
+	switch r.operator {
+	case selectorExists:
+		return r.key
+
+	case selectorDoesNotExist:
+		return "!" + r.key
+
+	case selectorIn, selectorNotIn:
+		return fmt.Sprintf("%s %s (%s)", r.key, r.operator, strings.Join(r.values, ","))
+	}
+
+	return r.key + string(r.operator) + strings.Join(r.values, ",")
+}
+
+// firstUnmatched returns the first requirement in the selector not
+// satisfied by the given labels, if any
+func (ls labelSelector) firstUnmatched(labels map[string]string) (labelRequirement, bool) {
+	for _, r := range ls {
+		if !r.matches(labels) {
+			return r, true
+		}
+	}
+
+	return labelRequirement{}, false
+}
+
+// String returns the selector using the label selector syntax
+func (ls labelSelector) String() string {
+	parts := make([]string, 0, len(ls))
+	for _, r := range ls {
+		parts = append(parts, r.String())
+	}
+
+	return strings.Join(parts, ",")
+}
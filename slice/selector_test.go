@@ -0,0 +1,134 @@
+package slice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseLabelSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     labelSelector
+		wantErr  bool
+	}{
+		{
+			name:     "equality",
+			selector: "app=web",
+			want:     labelSelector{{key: "app", operator: selectorEquals, values: []string{"web"}}},
+		},
+		{
+			name:     "double equals",
+			selector: "app==web",
+			want:     labelSelector{{key: "app", operator: selectorEquals, values: []string{"web"}}},
+		},
+		{
+			name:     "inequality with spaces",
+			selector: "tier != cache",
+			want:     labelSelector{{key: "tier", operator: selectorNotEquals, values: []string{"cache"}}},
+		},
+		{
+			name:     "empty value",
+			selector: "app=",
+			want:     labelSelector{{key: "app", operator: selectorEquals, values: []string{""}}},
+		},
+		{
+			name:     "prefixed key",
+			selector: "app.kubernetes.io/name=web",
+			want:     labelSelector{{key: "app.kubernetes.io/name", operator: selectorEquals, values: []string{"web"}}},
+		},
+		{
+			name:     "set based and existence",
+			selector: "app=web,tier!=cache,env in (prod, stage),release notin (canary),!legacy,team",
+			want: labelSelector{
+				{key: "app", operator: selectorEquals, values: []string{"web"}},
+				{key: "tier", operator: selectorNotEquals, values: []string{"cache"}},
+				{key: "env", operator: selectorIn, values: []string{"prod", "stage"}},
+				{key: "release", operator: selectorNotIn, values: []string{"canary"}},
+				{key: "legacy", operator: selectorDoesNotExist},
+				{key: "team", operator: selectorExists},
+			},
+		},
+		{
+			name:     "empty requirement",
+			selector: "app=web,,tier=cache",
+			wantErr:  true,
+		},
+		{
+			name:     "empty set",
+			selector: "env in ()",
+			wantErr:  true,
+		},
+		{
+			name:     "unclosed set",
+			selector: "env in (prod",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid operator",
+			selector: "app=~web",
+			wantErr:  true,
+		},
+		{
+			name:     "space in key",
+			selector: "my app=web",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseLabelSelector(tt.selector)
+			requireErrorIf(t, tt.wantErr, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_labelSelector_firstUnmatched(t *testing.T) {
+	labels := map[string]string{
+		"app":  "web",
+		"tier": "frontend",
+		"env":  "prod",
+	}
+
+	tests := []struct {
+		name     string
+		selector string
+		labels   map[string]string
+		want     bool
+	}{
+		{name: "equality match", selector: "app=web", labels: labels, want: true},
+		{name: "equality mismatch", selector: "app=api", labels: labels, want: false},
+		{name: "equality missing key", selector: "team=payments", labels: labels, want: false},
+		{name: "inequality match", selector: "tier!=cache", labels: labels, want: true},
+		{name: "inequality missing key", selector: "team!=payments", labels: labels, want: true},
+		{name: "inequality mismatch", selector: "tier!=frontend", labels: labels, want: false},
+		{name: "in match", selector: "env in (prod,stage)", labels: labels, want: true},
+		{name: "in mismatch", selector: "env in (dev,stage)", labels: labels, want: false},
+		{name: "notin match", selector: "env notin (dev,stage)", labels: labels, want: true},
+		{name: "notin missing key", selector: "team notin (payments)", labels: labels, want: true},
+		{name: "notin mismatch", selector: "env notin (prod)", labels: labels, want: false},
+		{name: "exists", selector: "app", labels: labels, want: true},
+		{name: "does not exist", selector: "!legacy", labels: labels, want: true},
+		{name: "does not exist mismatch", selector: "!app", labels: labels, want: false},
+		{name: "all requirements", selector: "app=web,tier!=cache,env in (prod,stage),!legacy", labels: labels, want: true},
+		{name: "one requirement fails", selector: "app=web,tier=cache", labels: labels, want: false},
+		{name: "no labels with equality", selector: "app=web", labels: nil, want: false},
+		{name: "no labels with negation", selector: "!app", labels: nil, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			selector, err := parseLabelSelector(tt.selector)
+			require.NoError(t, err)
+			_, unmatched := selector.firstUnmatched(tt.labels)
+			require.Equal(t, tt.want, !unmatched)
+		})
+	}
+}
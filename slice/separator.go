@@ -0,0 +1,59 @@
+package slice
+
+import "strings"
+
+// marker is the kind of YAML document marker found in a line
+type marker int
+
+const (
+	noMarker      marker = iota
+	documentStart        // "---", optionally followed by content on the same line
+	documentEnd          // "...", optionally followed by a comment
+)
+
+// parseMarker checks if the line is a YAML document marker. Markers must be
+// at the beginning of the line, and can only be followed by blanks or by
+// the end of the line: "---foo" is a regular value. For document start
+// markers, the content after the marker, like a comment, a tag or the
+// indicator of a block scalar, is returned alongside the line ending so it
+// can be kept as part of the document.
+func parseMarker(line string) (marker, string) {
+	text := strings.TrimRight(line, "\r\n")
+	ending := line[len(text):]
+
+	var kind marker
+	switch {
+	case strings.HasPrefix(text, "---"):
+		kind = documentStart
+
+	case strings.HasPrefix(text, "..."):
+		kind = documentEnd
+
+	default:
+		return noMarker, ""
+	}
+
+	rest := text[3:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return noMarker, ""
+	}
+
+	rest = strings.TrimLeft(rest, " \t")
+	if kind == documentEnd || rest == "" {
+		return kind, ""
+	}
+
+	return kind, rest + ending
+}
+
+// isBlankOrComment checks if the line has no YAML content
+func isBlankOrComment(line string) bool {
+	text := strings.TrimSpace(line)
+	return text == "" || strings.HasPrefix(text, "#")
+}
+
+// isDirective checks if the line is a YAML directive, like "%YAML 1.2" or
+// "%TAG ! tag:example.com,2000:"
+func isDirective(line string) bool {
+	return strings.HasPrefix(line, "%")
+}
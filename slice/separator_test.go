@@ -0,0 +1,84 @@
+package slice
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseMarker(t *testing.T) {
+	tests := []struct {
+		line     string
+		wantKind marker
+		wantRest string
+	}{
+		{line: "---\n", wantKind: documentStart},
+		{line: "---\r\n", wantKind: documentStart},
+		{line: "---", wantKind: documentStart},
+		{line: "---   \n", wantKind: documentStart},
+		{line: "---\t\n", wantKind: documentStart},
+		{line: "--- # comment\n", wantKind: documentStart, wantRest: "# comment\n"},
+		{line: "--- !!map\r\n", wantKind: documentStart, wantRest: "!!map\r\n"},
+		{line: "--- |\n", wantKind: documentStart, wantRest: "|\n"},
+		{line: "...\n", wantKind: documentEnd},
+		{line: "... # comment\n", wantKind: documentEnd},
+		{line: "---foo\n", wantKind: noMarker},
+		{line: "----\n", wantKind: noMarker},
+		{line: " ---\n", wantKind: noMarker},
+		{line: "....\n", wantKind: noMarker},
+		{line: "apiVersion: v1\n", wantKind: noMarker},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			t.Parallel()
+
+			kind, rest := parseMarker(tt.line)
+			require.Equal(t, tt.wantKind, kind)
+			require.Equal(t, tt.wantRest, rest)
+		})
+	}
+}
+
+// Test_separatorCorpus splits every YAML file in "testdata/separators" and
+// compares the documents found, with the line they start at and their exact
+// contents, to the ".golden" file next to it
+func Test_separatorCorpus(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "separators", "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(file)
+			require.NoError(t, err)
+
+			want, err := os.ReadFile(strings.TrimSuffix(file, ".yaml") + ".golden")
+			require.NoError(t, err)
+
+			s := &Split{
+				opts:   Options{Join: true},
+				log:    nolog,
+				inputs: []input{{name: filepath.Base(file), data: data}},
+			}
+
+			var got strings.Builder
+			if err := s.scan(); err != nil {
+				fmt.Fprintf(&got, "error: %s\n", err)
+			}
+
+			for _, v := range s.filesFound {
+				doc := v.documents[0]
+				fmt.Fprintf(&got, "=== document %d at line %d: %s/%s\n%s\n", doc.index, doc.line, orNone(doc.meta.Kind), orNone(doc.meta.Name), v.data)
+			}
+
+			require.Equal(t, string(want), got.String())
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package slice
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// discardLogger is used by sinks created without a logger
+var discardLogger Logger = log.New(io.Discard, "", 0)
+
+// Sink receives the files generated. The path is the file name rendered for
+// the file, relative to the output, and the metadata is the one of the last
+// resource merged into it, or empty for generated files like kustomization
+// files. A file is written again if its contents are replaced, and the data
+// is never modified afterwards, so sinks can keep it.
+//
+// If a sink also implements io.Closer, it's closed once all the files are
+// written.
+type Sink interface {
+	Write(path string, data []byte, meta Metadata) error
+}
+
+// AppendSink is a Sink that can also append data to a file already written.
+// It's required when streaming resources that render the same file name,
+// since they're appended to the file as soon as they're found.
+type AppendSink interface {
+	Sink
+	Append(path string, data []byte, meta Metadata) error
+}
+
+// printf writes a single line to the writer, if any
+func printf(w io.Writer, format string, args ...interface{}) {
+	if w != nil {
+		fmt.Fprintf(w, format+"\n", args...)
+	}
+}
+
+// DirectorySink writes the files to a directory on disk, creating any
+// folder needed. Files resolving outside the directory through a symlink
+// are rejected.
+type DirectorySink struct {
+	Directory string    // the directory where the files are written
+	Stderr    io.Writer // if set, a line is printed for every file written
+
+	log Logger
+}
+
+func (d *DirectorySink) resolve(name string, meta Metadata) (string, error) {
+	fullpath := filepath.Join(d.Directory, name)
+
+	// Ensure no symlink inside the output directory can be used to write
+	// files outside of it
+	within, err := resolvesWithin(d.Directory, fullpath)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve path for file %q: %w", fullpath, err)
+	}
+
+	if !within {
+		if meta.Kind == "" && meta.Name == "" {
+			return "", fmt.Errorf("file %q resolves outside the output directory %q through a symlink", fullpath, d.Directory)
+		}
+
+		return "", fmt.Errorf("file %q for resource %s %q resolves outside the output directory %q through a symlink", fullpath, meta.Kind, meta.Name, d.Directory)
+	}
+
+	return fullpath, nil
+}
+
+// Write creates or truncates the file and writes the data to it
+func (d *DirectorySink) Write(name string, data []byte, meta Metadata) error {
+	fullpath, err := d.resolve(name, meta)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFile(d.logger(), fullpath, data); err != nil {
+		return err
+	}
+
+	printf(d.Stderr, "Wrote %s -- %d bytes.", fullpath, len(data))
+	return nil
+}
+
+// Append adds the data at the end of a file already written
+func (d *DirectorySink) Append(name string, data []byte, meta Metadata) error {
+	fullpath, err := d.resolve(name, meta)
+	if err != nil {
+		return err
+	}
+
+	if err := appendFile(d.logger(), fullpath, data); err != nil {
+		return err
+	}
+
+	printf(d.Stderr, "Appended to %s -- %d bytes.", fullpath, len(data))
+	return nil
+}
+
+func (d *DirectorySink) logger() Logger {
+	if d.log == nil {
+		return discardLogger
+	}
+
+	return d.log
+}
+
+// StdoutSink prints the files, one after the other, separated by "---". A
+// "# File:" comment with the path and size of the file precedes each one,
+// unless file comments are removed. Since files are already separated, a
+// "---" at the beginning of the data isn't printed.
+type StdoutSink struct {
+	Stdout             io.Writer // where the files are printed
+	RemoveFileComments bool      // if true, the "# File:" comments aren't printed
+
+	printed int
+}
+
+// Write prints the file
+func (p *StdoutSink) Write(name string, data []byte, _ Metadata) error {
+	data = bytes.TrimPrefix(data, []byte("---\n"))
+	data = bytes.TrimSuffix(data, []byte("\n"))
+
+	if p.printed > 0 {
+		printf(p.Stdout, "---")
+	}
+
+	if !p.RemoveFileComments {
+		printf(p.Stdout, "# File: %s (%d bytes)", name, len(data))
+	}
+
+	printf(p.Stdout, "%s", data)
+	p.printed++
+	return nil
+}
+
+// Append prints the data appended as if it were a new file, since the file
+// it's appended to was already printed
+func (p *StdoutSink) Append(name string, data []byte, meta Metadata) error {
+	return p.Write(name, data, meta)
+}
+
+// DryRunSink doesn't write anything, and only prints what would be written
+type DryRunSink struct {
+	Directory string    // the directory where the files would be written
+	Stderr    io.Writer // where a line is printed for every file
+}
+
+// Write prints the file that would be written
+func (d *DryRunSink) Write(name string, data []byte, _ Metadata) error {
+	printf(d.Stderr, "Would write %s -- %d bytes.", filepath.Join(d.Directory, name), len(data))
+	return nil
+}
+
+// Append prints the file that would be appended to
+func (d *DryRunSink) Append(name string, data []byte, _ Metadata) error {
+	printf(d.Stderr, "Would append to %s -- %d bytes.", filepath.Join(d.Directory, name), len(data))
+	return nil
+}
+
+// MemoryFile is a file kept in memory by a MemorySink
+type MemoryFile struct {
+	Path     string
+	Data     []byte
+	Metadata Metadata
+}
+
+// MemorySink keeps the files in memory, in the order they were first
+// written. The zero value is ready to use.
+type MemorySink struct {
+	Files []MemoryFile
+
+	index map[string]int // position of every path in the files
+}
+
+// find returns the position of the file with the given path. The index is
+// rebuilt if the files were modified, like when they're reset.
+func (m *MemorySink) find(name string) (int, bool) {
+	if m.index == nil || len(m.index) != len(m.Files) {
+		m.index = make(map[string]int, len(m.Files))
+		for pos, f := range m.Files {
+			m.index[f.Path] = pos
+		}
+	}
+
+	pos, found := m.index[name]
+	return pos, found
+}
+
+// Write adds the file, replacing its contents if it was already written
+func (m *MemorySink) Write(name string, data []byte, meta Metadata) error {
+	if pos, found := m.find(name); found {
+		m.Files[pos].Data, m.Files[pos].Metadata = data, meta
+		return nil
+	}
+
+	m.Files = append(m.Files, MemoryFile{Path: name, Data: data, Metadata: meta})
+	m.index[name] = len(m.Files) - 1
+	return nil
+}
+
+// Append adds the data at the end of a file already written
+func (m *MemorySink) Append(name string, data []byte, meta Metadata) error {
+	pos, found := m.find(name)
+	if !found {
+		return fmt.Errorf("unable to append to file %q: file not found", name)
+	}
+
+	m.Files[pos].Data = append(m.Files[pos].Data[:len(m.Files[pos].Data):len(m.Files[pos].Data)], data...)
+	return nil
+}
+
+// File returns the contents of the file with the given path
+func (m *MemorySink) File(name string) ([]byte, bool) {
+	if pos, found := m.find(name); found {
+		return m.Files[pos].Data, true
+	}
+
+	return nil, false
+}
+
+// outputSink returns the sink files are written to: the one in the options,
+// if any, or the built-in one for the output selected
+func (s *Split) outputSink() Sink {
+	stderr := s.opts.Stderr
+	if s.opts.Quiet {
+		stderr = nil
+	}
+
+	switch {
+	case s.opts.Sink != nil:
+		return s.opts.Sink
+
+	case s.opts.DryRun:
+		return &DryRunSink{Directory: s.opts.OutputDirectory, Stderr: stderr}
+
+	case s.opts.OutputToStdout:
+		return &StdoutSink{Stdout: s.opts.Stdout, RemoveFileComments: s.opts.RemoveFileComments}
+
+	case s.opts.OutputArchive != "":
+		return &ArchiveSink{Path: s.opts.OutputArchive, Stderr: stderr}
+
+	default:
+		return &DirectorySink{Directory: s.opts.OutputDirectory, Stderr: stderr, log: s.log}
+	}
+}
+
+// writeFile creates or truncates the file, and any folder needed, and
+// writes the data to it, adding a trailing line break if missing
+func writeFile(log Logger, path string, data []byte) error {
+	// Since a single Go Template File Name might render different folder prefixes,
+	// we need to ensure they're all created.
+	if err := os.MkdirAll(filepath.Dir(path), folderChmod); err != nil {
+		return fmt.Errorf("unable to create output folder for file %q: %w", path, err)
+	}
+
+	// Open the file as read/write, create the file if it doesn't exist, and if
+	// it does, truncate it.
+	log.Printf("Opening file path %q for writing", path)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, defaultChmod)
+	if err != nil {
+		return fmt.Errorf("unable to create/open file %q: %w", path, err)
+	}
+
+	defer f.Close()
+
+	// Check if the last character is a newline, and if not, add one
+	if !bytes.HasSuffix(data, []byte{'\n'}) {
+		log.Printf("Adding new line to end of contents (content did not end on a line break)")
+		data = append(data[:len(data):len(data)], '\n')
+	}
+
+	// Write the entire file buffer back to the file in disk
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("unable to write file contents for file %q: %w", path, err)
+	}
+
+	// Attempt to close the file cleanly
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close file after write for file %q: %w", path, err)
+	}
+
+	return nil
+}
+
+// appendFile adds the data at the end of an existing file
+func appendFile(log Logger, path string, data []byte) error {
+	log.Printf("Opening file path %q for appending", path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, defaultChmod)
+	if err != nil {
+		return fmt.Errorf("unable to open file %q: %w", path, err)
+	}
+
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("unable to write file contents for file %q: %w", path, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close file after write for file %q: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package slice
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeOnlySink is a sink that can't append to files, and records whether
+// it was closed
+type writeOnlySink struct {
+	files  MemorySink
+	closed bool
+}
+
+func (w *writeOnlySink) Write(path string, data []byte, meta Metadata) error {
+	return w.files.Write(path, data, meta)
+}
+
+func (w *writeOnlySink) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestExecute_sink(t *testing.T) {
+	input := `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: prod
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: staging
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+`
+
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "default", opts: Options{GoTemplate: "{{.kind | lower}}/{{.metadata.name}}.yaml"}},
+		{name: "triple dash", opts: Options{GoTemplate: "{{.metadata.name}}.yaml", IncludeTripleDash: true}},
+		{name: "overwrite", opts: Options{GoTemplate: "{{.metadata.name}}.yaml", OnConflict: ConflictOverwrite}},
+		{name: "kustomize", opts: Options{GoTemplate: "{{.metadata.namespace}}/{{.kind | lower}}.yaml", Kustomize: true}},
+		{name: "stream", opts: Options{GoTemplate: "{{.metadata.name}}.yaml", Stream: true}},
+		{name: "stream with suffixes", opts: Options{GoTemplate: "{{.metadata.name}}.yaml", OnConflict: ConflictSuffix, Stream: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// The files written to a sink must be the same ones written
+			// to the output directory
+			tdoutput := t.TempDir()
+
+			opts := tt.opts
+			opts.InputFile = filepath.Join(tdinput, "input.yaml")
+			opts.OutputDirectory = tdoutput
+			opts.Stdout = io.Discard
+			opts.Stderr = io.Discard
+
+			s, err := New(opts)
+			require.NoError(t, err)
+			require.NoError(t, s.Execute())
+
+			var stderr bytes.Buffer
+			sink := &MemorySink{}
+
+			opts = tt.opts
+			opts.InputFile = filepath.Join(tdinput, "input.yaml")
+			opts.Sink = sink
+			opts.Stdout = io.Discard
+			opts.Stderr = &stderr
+
+			s, err = New(opts)
+			require.NoError(t, err)
+			require.NoError(t, s.Execute())
+
+			got := make(map[string]string)
+			for _, f := range sink.Files {
+				got[filepath.ToSlash(f.Path)] = string(f.Data)
+			}
+
+			require.Equal(t, readTree(t, tdoutput), got)
+			require.Contains(t, stderr.String(), "generated.")
+			require.NotContains(t, stderr.String(), "Wrote", "sinks print their own messages")
+		})
+	}
+}
+
+func TestExecute_sinkMetadata(t *testing.T) {
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  labels:\n    app: web\n"), 0o644))
+
+	sink := &writeOnlySink{}
+	s, err := New(Options{
+		InputFile:  filepath.Join(tdinput, "input.yaml"),
+		GoTemplate: DefaultTemplateName,
+		Kustomize:  true,
+		Sink:       sink,
+		Stdout:     io.Discard,
+		Stderr:     io.Discard,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Execute())
+
+	require.True(t, sink.closed, "the sink should be closed once all the files are written")
+	require.Equal(t, []MemoryFile{
+		{
+			Path:     "service-web.yaml",
+			Data:     []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  labels:\n    app: web\n"),
+			Metadata: Metadata{APIVersion: "v1", Kind: "Service", Name: "web", Labels: map[string]string{"app": "web"}},
+		},
+		{
+			Path: "kustomization.yaml",
+			Data: []byte("apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n  - service-web.yaml\n"),
+		},
+	}, sink.files.Files)
+}
+
+func TestNew_sink(t *testing.T) {
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n"), 0o644))
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{
+			name: "sink",
+			opts: Options{Sink: &MemorySink{}},
+		},
+		{
+			name:    "sink and output directory",
+			opts:    Options{Sink: &MemorySink{}, OutputDirectory: "out"},
+			wantErr: "cannot specify both a sink and an output directory, archive, output to stdout or dry-run",
+		},
+		{
+			name:    "sink and dry-run",
+			opts:    Options{Sink: &MemorySink{}, DryRun: true},
+			wantErr: "cannot specify both a sink and an output directory, archive, output to stdout or dry-run",
+		},
+		{
+			name:    "sink and prune",
+			opts:    Options{Sink: &MemorySink{}, PruneOutputDir: true},
+			wantErr: "cannot prune the output directory when writing to a sink",
+		},
+		{
+			name:    "sink and join",
+			opts:    Options{Sink: &MemorySink{}, Join: true},
+			wantErr: "cannot specify an output directory, archive, sink or output to stdout when joining resources: use the output file instead",
+		},
+		{
+			name:    "streaming to a sink that can't append",
+			opts:    Options{Sink: &writeOnlySink{}, Stream: true},
+			wantErr: "cannot stream resources to a sink that can't append to files: use a different conflict strategy",
+		},
+		{
+			name: "streaming to a sink that can't append with suffixes",
+			opts: Options{Sink: &writeOnlySink{}, Stream: true, OnConflict: ConflictSuffix},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.opts.InputFile = filepath.Join(tdinput, "input.yaml")
+			tt.opts.GoTemplate = DefaultTemplateName
+
+			_, err := New(tt.opts)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSinks(t *testing.T) {
+	meta := Metadata{Kind: "Pod", Name: "web"}
+
+	t.Run("stdout", func(t *testing.T) {
+		t.Parallel()
+
+		var stdout bytes.Buffer
+		sink := &StdoutSink{Stdout: &stdout}
+
+		require.NoError(t, sink.Write("pod.yaml", []byte("---\nkind: Pod\n"), meta))
+		require.NoError(t, sink.Append("pod.yaml", []byte("---\nkind: Service\n"), meta))
+		require.Equal(t, "# File: pod.yaml (9 bytes)\nkind: Pod\n---\n# File: pod.yaml (13 bytes)\nkind: Service\n", stdout.String())
+	})
+
+	t.Run("dry-run", func(t *testing.T) {
+		t.Parallel()
+
+		var stderr bytes.Buffer
+		sink := &DryRunSink{Directory: "out", Stderr: &stderr}
+
+		require.NoError(t, sink.Write("pod.yaml", []byte("kind: Pod\n"), meta))
+		require.NoError(t, sink.Append("pod.yaml", []byte("---\nkind: Service\n"), meta))
+		require.Equal(t, "Would write "+filepath.Join("out", "pod.yaml")+" -- 10 bytes.\nWould append to "+filepath.Join("out", "pod.yaml")+" -- 18 bytes.\n", stderr.String())
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		t.Parallel()
+
+		dir := filepath.Join(t.TempDir(), "out")
+		sink := &DirectorySink{Directory: dir}
+
+		require.NoError(t, sink.Write(filepath.Join("pods", "pod.yaml"), []byte("kind: Pod"), meta))
+		require.NoError(t, sink.Append(filepath.Join("pods", "pod.yaml"), []byte("---\nkind: Service\n"), meta))
+		require.Error(t, sink.Append("missing.yaml", []byte("kind: Pod\n"), meta))
+		require.Equal(t, map[string]string{"pods/pod.yaml": "kind: Pod\n---\nkind: Service\n"}, readTree(t, dir))
+	})
+
+	t.Run("memory", func(t *testing.T) {
+		t.Parallel()
+
+		sink := &MemorySink{}
+
+		require.NoError(t, sink.Write("pod.yaml", []byte("kind: Pod\n"), meta))
+		require.NoError(t, sink.Write("service.yaml", []byte("kind: Service\n"), Metadata{Kind: "Service"}))
+		require.NoError(t, sink.Append("pod.yaml", []byte("---\nkind: Pod\n"), meta))
+		require.NoError(t, sink.Write("service.yaml", []byte("kind: Service\nspec: {}\n"), Metadata{Kind: "Service"}))
+		require.EqualError(t, sink.Append("missing.yaml", nil, meta), `unable to append to file "missing.yaml": file not found`)
+
+		data, ok := sink.File("pod.yaml")
+		require.True(t, ok)
+		require.Equal(t, "kind: Pod\n---\nkind: Pod\n", string(data))
+
+		_, ok = sink.File("missing.yaml")
+		require.False(t, ok)
+
+		require.Len(t, sink.Files, 2)
+		require.Equal(t, "service.yaml", sink.Files[1].Path)
+		require.Equal(t, "kind: Service\nspec: {}\n", string(sink.Files[1].Data))
+
+		// Files can be reset between runs
+		sink.Files = nil
+		require.NoError(t, sink.Write("service.yaml", []byte("kind: Service\n"), Metadata{Kind: "Service"}))
+		require.NoError(t, sink.Write("pod.yaml", []byte("kind: Pod\n"), meta))
+		require.NoError(t, sink.Write("service.yaml", []byte("kind: Service\nspec: {}\n"), Metadata{Kind: "Service"}))
+		require.Equal(t, []MemoryFile{
+			{Path: "service.yaml", Data: []byte("kind: Service\nspec: {}\n"), Metadata: Metadata{Kind: "Service"}},
+			{Path: "pod.yaml", Data: []byte("kind: Pod\n"), Metadata: meta},
+		}, sink.Files)
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		t.Parallel()
+
+		fp := filepath.Join(t.TempDir(), "out", "manifests.zip")
+		sink := &ArchiveSink{Path: fp}
+
+		require.NoError(t, sink.Write(filepath.Join("pods", "pod.yaml"), []byte("kind: Pod\n"), meta))
+		require.NoError(t, sink.Write("service.yaml", []byte("kind: Service\n"), meta))
+		require.NoError(t, sink.Write(filepath.Join("pods", "pod.yaml"), []byte("kind: Pod\nspec: {}\n"), meta))
+
+		_, err := os.Stat(fp)
+		require.True(t, os.IsNotExist(err), "the archive should only be written when the sink is closed")
+
+		require.NoError(t, sink.Close())
+
+		data, err := os.ReadFile(fp)
+		require.NoError(t, err)
+
+		inputs, err := loadarchive(extensions, fp, archiveZip, data, true)
+		require.NoError(t, err)
+		require.Len(t, inputs, 2)
+		require.Equal(t, "pods/pod.yaml", inputs[0].path)
+		require.Equal(t, "kind: Pod\nspec: {}\n", string(inputs[0].data))
+	})
+}
@@ -1,10 +1,10 @@
 package slice
 
 import (
-	"bytes"
 	"io"
 	"log"
 	"os"
+	"regexp"
 	"text/template"
 )
 
@@ -25,16 +25,37 @@ type Split struct {
 	opts     Options
 	log      Logger
 	template *template.Template
-	data     *bytes.Buffer
+	inputs   []input
+	source   string // name of the input being scanned
+	path     string // path of the input being scanned, relative to the folder, glob or archive it was found in
+	line     int    // line of the input where the current document starts
 
 	filesFound []yamlFile
+	fileIndex  map[string]int    // position of every file name in the files found
+	suffixed   map[string]string // file names given by the suffix strategy, with the name originally rendered
 	fileCount  int
+	skipped    []skippedDocument
+	sink       Sink      // where the files are written
+	pipeline   *pipeline // documents being parsed concurrently while scanning
+	listing    bool      // if true, documents are only being listed and nothing is written
+
+	labelSelector labelSelector
+	where         []*whereExpression
+	includedRegex []*regexp.Regexp
+	excludedRegex []*regexp.Regexp
 }
 
 // New creates a new Split instance with the options set
 func New(opts Options) (*Split, error) {
+	return newSplit(opts, false)
+}
+
+// newSplit creates a new Split instance. When only listing documents, the
+// output options aren't validated, since nothing is written.
+func newSplit(opts Options, listing bool) (*Split, error) {
 	s := &Split{
-		log: log.New(io.Discard, "[debug] ", log.Lshortfile),
+		log:     log.New(io.Discard, "[debug] ", log.Lshortfile),
+		listing: listing,
 	}
 
 	if opts.Stdout == nil {
@@ -62,11 +83,14 @@ func New(opts Options) (*Split, error) {
 type Options struct {
 	Stdout io.Writer
 	Stderr io.Writer
+	Input  io.Reader // if set, the resources are read from it instead of the input files or folder
+	Sink   Sink      // if set, the files are written to it instead of the output directory, stdout or output archive
 
-	InputFile         string   // the name of the input file to be read
+	InputFile         string   // the name of the input file to be read, which can also be a .tar.gz, .tgz, .tar or .zip archive
+	InputFiles        []string // files, folders or glob patterns to be read after the input file, "-" for stdin
 	InputFolder       string   // the name of the input folder to be read
 	InputFolderExt    []string // the extensions of the files to be read
-	Recurse           bool     // if true, the input folder will be read recursively
+	Recurse           bool     // if true, the input folder or archive will be read recursively
 	OutputDirectory   string   // the path to the directory where the files will be stored
 	PruneOutputDir    bool     // if true, the output directory will be pruned before writing the files
 	OutputToStdout    bool     // if true, the output will be written to stdout instead of a file
@@ -74,7 +98,20 @@ type Options struct {
 	DryRun            bool     // if true, no files are created
 	DebugMode         bool     // enables debug mode
 	Quiet             bool     // disables all writing to stdout/stderr
+	Explain           bool     // prints to stderr why each resource was included or skipped
+	Report            string   // path where a JSON report of the run is written, "-" for stdout
+	OutputArchive     string   // if set, files are written to this .tar.gz, .tgz, .tar or .zip archive instead of the output directory
+	Kustomize         bool     // if true, a kustomization.yaml listing the files generated is created or updated in every output folder
+	Join              bool     // if true, resources are joined into a single YAML stream instead of being sliced
+	OutputFile        string   // the file where joined resources are written, stdout if empty
+	SourceComments    bool     // if true, joined resources are preceded by a "# Source:" comment with the file they came from
+	OnConflict        string   // what to do when resources render the same file name, one of the Conflict* strategies, defaults to ConflictAppend
 	IncludeTripleDash bool     // include the "---" separator on resources sliced
+	OutputFormat      string   // if set, resources are re-encoded to one of the OutputFormat* formats instead of keeping their original contents
+	AllowJSON         bool     // if true, JSON inputs with a top-level array or a stream of objects are accepted, and ".json" is added to the default extensions
+	ExpandLists       bool     // if true, resources of kind "List" or "*List" are expanded into their items
+	Workers           int      // the amount of documents parsed concurrently, defaults to the number of CPUs available
+	Stream            bool     // if true, resources are written as soon as they're read instead of keeping the entire input in memory, which can't be combined with sorting, joining or output archives
 
 	IncludedKinds    []string
 	ExcludedKinds    []string
@@ -82,7 +119,9 @@ type Options struct {
 	ExcludedNames    []string
 	Included         []string
 	Excluded         []string
-	StrictKubernetes bool // if true, any YAMLs that don't contain at least an "apiVersion", "kind" and "metadata.name" will be excluded
+	IncludedRegex    []string // regular expressions matched against "<kind>/<name>" to include resources
+	ExcludedRegex    []string // regular expressions matched against "<kind>/<name>" to exclude resources
+	StrictKubernetes bool     // if true, any YAMLs that don't contain at least an "apiVersion", "kind" and "metadata.name" will be excluded
 
 	SortByKind         bool // if true, it will sort the resources by kind
 	RemoveFileComments bool // if true, it will remove comments generated by the app from the generated files
@@ -90,6 +129,19 @@ type Options struct {
 	AllowEmptyNames bool
 	AllowEmptyKinds bool
 
-	IncludedGroups []string
-	ExcludedGroups []string
+	IncludedGroups []string // API groups to include, glob supported, "core" matches the core group
+	ExcludedGroups []string // API groups to exclude, glob supported, "core" matches the core group
+
+	IncludedAPIVersions []string // API versions to include, like "v1beta1" or "apps/v1", glob supported
+	ExcludedAPIVersions []string // API versions to exclude, like "v1beta1" or "apps/v1", glob supported
+
+	LabelSelector string // a Kubernetes label selector, like "app=web,tier!=cache", to filter resources
+
+	IncludedNamespaces []string // namespaces to include, glob supported, "<none>" matches resources without namespace
+	ExcludedNamespaces []string // namespaces to exclude, glob supported, "<none>" matches resources without namespace
+
+	IncludedAnnotations []string // annotations to include, as "key" or "key=value", glob supported
+	ExcludedAnnotations []string // annotations to exclude, as "key" or "key=value", glob supported
+
+	Where []string // expressions evaluated against each resource, all of them must match for it to be included
 }
@@ -0,0 +1,187 @@
+package slice
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+)
+
+// streamHeadSize is the amount of data read from the beginning of streamed
+// inputs to find out if they're archives or JSON
+const streamHeadSize = 4096
+
+// readCloser reads from a buffered reader and closes the file behind it
+type readCloser struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// openInput returns a function that opens the file at fp for reading
+func openInput(fp string) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return openFile(fp)
+	}
+}
+
+// streamInput prepares the file at fp to be streamed, returning the input
+// alongside the first bytes of it. Regular files are opened again when
+// they're scanned, while stdin or pipes, which can only be read once, keep
+// the data already read. Stdin is always read once, even when it's
+// redirected from a regular file, since opening it again returns the
+// same handle.
+func streamInput(fp, name, rel string) (input, []byte, error) {
+	f, err := openFile(fp)
+	if err != nil {
+		return input{}, nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() || f == os.Stdin {
+		return streamReader(f, name, rel)
+	}
+
+	defer f.Close()
+
+	head, err := bufio.NewReaderSize(f, streamHeadSize).Peek(streamHeadSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return input{}, nil, fmt.Errorf("unable to read file %q: %w", fp, err)
+	}
+
+	return input{name: name, path: rel, open: openInput(fp), size: fi.Size()}, head, nil
+}
+
+// streamReader prepares a reader that can only be read once to be
+// streamed, returning the input alongside the first bytes of it
+func streamReader(rc io.ReadCloser, name, rel string) (input, []byte, error) {
+	r := bufio.NewReaderSize(rc, streamHeadSize)
+	head, err := r.Peek(streamHeadSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		rc.Close()
+		return input{}, nil, fmt.Errorf("unable to read %s: %w", name, err)
+	}
+
+	in := input{name: name, path: rel, size: int64(len(head))}
+	in.open = func() (io.ReadCloser, error) {
+		return readCloser{Reader: r, Closer: rc}, nil
+	}
+
+	return in, head, nil
+}
+
+// readAll reads the entire contents of a streamed input
+func (in input) readAll() ([]byte, error) {
+	rc, err := in.open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %q: %w", in.name, err)
+	}
+
+	return data, nil
+}
+
+// scanStream scans an input while it's being read. JSON inputs are the
+// exception, since they're read in full to find the line each object
+// starts at.
+func (s *Split) scanStream(in input) error {
+	rc, err := in.open()
+	if err != nil {
+		return err
+	}
+
+	defer rc.Close()
+
+	r := bufio.NewReaderSize(rc, streamHeadSize)
+
+	if s.opts.AllowJSON {
+		if head, _ := r.Peek(streamHeadSize); isJSON(head) {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return fmt.Errorf("unable to read file %q: %w", in.name, err)
+			}
+
+			return s.scanJSON(data)
+		}
+	}
+
+	return s.scanInput(r)
+}
+
+// stream writes the document just merged into the file at the given
+// position right away, either as a new file or appended to it. Only the
+// file name and the documents in it are kept afterwards, so memory usage
+// doesn't grow with the size of the input.
+func (s *Split) stream(position int, data []byte, appended bool) error {
+	if !s.opts.Stream || s.listing {
+		return nil
+	}
+
+	v := &s.filesFound[position]
+	v.data = nil
+
+	// The documents merged are only needed for the report, besides the
+	// first one, which is used to describe file name conflicts
+	if s.opts.Report == "" {
+		v.documents = v.documents[:1]
+	}
+
+	// Appended documents are preceded by the separator, without the line
+	// break before it, since the file already ends with one
+	var contents []byte
+	if appended {
+		contents = append([]byte("---\n"), data...)
+		if !bytes.HasSuffix(contents, []byte{'\n'}) {
+			contents = append(contents, '\n')
+		}
+	} else {
+		contents = s.fileContents(yamlFile{data: data})
+	}
+
+	if s.opts.Report != "" {
+		if !appended || v.digest == nil {
+			v.size, v.digest = 0, sha256.New()
+		}
+
+		v.size += len(contents)
+		v.digest.Write(contents)
+	}
+
+	s.log.Printf("Streaming file %q: %d bytes long.", v.filename, len(contents))
+
+	if !appended {
+		return s.sink.Write(v.filename, contents, v.meta.metadata())
+	}
+
+	sink, ok := s.sink.(AppendSink)
+	if !ok {
+		return fmt.Errorf("unable to append resource %s %q to file %q: the sink doesn't support appending to files", v.meta.Kind, v.meta.Name, v.filename)
+	}
+
+	return sink.Append(v.filename, contents, v.meta.metadata())
+}
+
+// executeStream prepares the output, then scans the inputs writing every
+// file as soon as its resources are found
+func (s *Split) executeStream() error {
+	if err := s.prepareOutput(); err != nil {
+		return err
+	}
+
+	if err := s.scan(); err != nil {
+		return err
+	}
+
+	if err := s.finishOutput(); err != nil {
+		return err
+	}
+
+	return s.writeReport()
+}
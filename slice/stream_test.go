@@ -0,0 +1,380 @@
+package slice
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readTree returns the contents of every file in the folder, keyed by their
+// slash-separated path relative to it
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(fp string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		data, err := os.ReadFile(fp)
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, fp)
+		if err != nil {
+			return err
+		}
+
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	require.NoError(t, err)
+
+	return files
+}
+
+func TestExecute_stream(t *testing.T) {
+	input := `# The namespace
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: prod
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: staging
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: prod
+`
+
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "default", opts: Options{GoTemplate: DefaultTemplateName}},
+		{name: "append", opts: Options{GoTemplate: "{{.metadata.name}}.yaml"}},
+		{name: "append with triple dash", opts: Options{GoTemplate: "{{.metadata.name}}.yaml", IncludeTripleDash: true}},
+		{name: "json with suffixes", opts: Options{GoTemplate: "{{.metadata.name}}.json", OutputFormat: OutputFormatJSON, OnConflict: ConflictSuffix}},
+		{name: "suffix", opts: Options{GoTemplate: "{{.metadata.name}}.yaml", OnConflict: ConflictSuffix}},
+		{name: "skip", opts: Options{GoTemplate: "{{.metadata.name}}.yaml", OnConflict: ConflictSkip}},
+		{name: "overwrite", opts: Options{GoTemplate: "{{.metadata.name}}.yaml", OnConflict: ConflictOverwrite}},
+		{name: "filtered", opts: Options{GoTemplate: DefaultTemplateName, ExcludedKinds: []string{"Service"}}},
+		{name: "kustomize", opts: Options{GoTemplate: "{{.kind | lower}}/{{.metadata.name}}.yaml", Kustomize: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tdinput := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+			// Streaming must generate the same files and report as
+			// reading the entire input first
+			run := func(stream bool) (map[string]string, report) {
+				tdoutput := t.TempDir()
+				reportPath := filepath.Join(t.TempDir(), "report.json")
+
+				opts := tt.opts
+				opts.InputFile = filepath.Join(tdinput, "input.yaml")
+				opts.OutputDirectory = tdoutput
+				opts.Report = reportPath
+				opts.Stream = stream
+				opts.Stdout = io.Discard
+				opts.Stderr = io.Discard
+
+				s, err := New(opts)
+				require.NoError(t, err)
+				require.NoError(t, s.Execute())
+
+				data, err := os.ReadFile(reportPath)
+				require.NoError(t, err)
+
+				var r report
+				require.NoError(t, json.Unmarshal(data, &r))
+
+				r.OutputDirectory = ""
+				for i := range r.Files {
+					r.Files[i].Path = strings.TrimPrefix(r.Files[i].Path, tdoutput)
+				}
+
+				return readTree(t, tdoutput), r
+			}
+
+			wantFiles, wantReport := run(false)
+			gotFiles, gotReport := run(true)
+
+			require.NotEmpty(t, wantFiles)
+			require.Equal(t, wantFiles, gotFiles)
+			require.Equal(t, wantReport, gotReport)
+		})
+	}
+}
+
+func TestExecute_streamStdout(t *testing.T) {
+	input := "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: prod\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"
+
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+	var stdout, stderr bytes.Buffer
+	s, err := New(Options{
+		GoTemplate:     "{{.metadata.name}}.yaml",
+		InputFile:      filepath.Join(tdinput, "input.yaml"),
+		OutputToStdout: true,
+		Stream:         true,
+		Stdout:         &stdout,
+		Stderr:         &stderr,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Execute())
+
+	// Documents are printed in the order they're read, even if they're
+	// appended to a file printed before
+	require.Equal(t, `# File: web.yaml (50 bytes)
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+# File: prod.yaml (53 bytes)
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: prod
+---
+# File: web.yaml (58 bytes)
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+`, stdout.String())
+	require.Equal(t, "2 files parsed to stdout.\n", stderr.String())
+}
+
+func TestExecute_streamStdinFromFile(t *testing.T) {
+	// Not parallel: the test replaces os.Stdin with a regular file, like
+	// when the input is redirected with "< input.yaml"
+	input := "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n---\napiVersion: v1\nkind: Namespace\nmetadata:\n  name: prod\n"
+
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte(input), 0o644))
+
+	f, err := os.Open(filepath.Join(tdinput, "input.yaml"))
+	require.NoError(t, err)
+	defer f.Close()
+
+	stdin := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = stdin }()
+
+	tdoutput := t.TempDir()
+	s, err := New(Options{
+		GoTemplate:      "{{.metadata.name}}.yaml",
+		InputFile:       "-",
+		OutputDirectory: tdoutput,
+		Stream:          true,
+		Stderr:          io.Discard,
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.Execute())
+
+	web, err := os.ReadFile(filepath.Join(tdoutput, "web.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n", string(web))
+
+	prod, err := os.ReadFile(filepath.Join(tdoutput, "prod.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: prod\n", string(prod))
+}
+
+func TestNew_stream(t *testing.T) {
+	tdinput := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "input.yaml"), []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tdinput, "empty.yaml"), nil, 0o644))
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr string
+	}{
+		{
+			name: "output directory",
+			opts: Options{OutputDirectory: "out"},
+		},
+		{
+			name:    "sort by kind",
+			opts:    Options{OutputDirectory: "out", SortByKind: true},
+			wantErr: "cannot sort resources by kind when streaming: sorting requires reading all the resources first",
+		},
+		{
+			name:    "join",
+			opts:    Options{Join: true},
+			wantErr: "cannot stream resources when joining them",
+		},
+		{
+			name:    "output archive",
+			opts:    Options{OutputArchive: "out.tar.gz"},
+			wantErr: "cannot stream resources to an output archive",
+		},
+		{
+			name:    "overwrite on stdout",
+			opts:    Options{OutputToStdout: true, OnConflict: ConflictOverwrite},
+			wantErr: "cannot overwrite resources already printed to stdout when streaming: use a different conflict strategy",
+		},
+		{
+			name:    "empty input",
+			opts:    Options{InputFile: filepath.Join(tdinput, "empty.yaml"), OutputDirectory: "out"},
+			wantErr: "no data found in input file or folder",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if tt.opts.InputFile == "" {
+				tt.opts.InputFile = filepath.Join(tdinput, "input.yaml")
+			}
+
+			tt.opts.GoTemplate = DefaultTemplateName
+			tt.opts.Stream = true
+
+			s, err := New(tt.opts)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Len(t, s.inputs, 1)
+			require.Nil(t, s.inputs[0].data, "streamed inputs should not be loaded ahead of time")
+			require.NotNil(t, s.inputs[0].open)
+		})
+	}
+}
+
+// generateInput writes a YAML stream with the given amount of documents to
+// a file, cycling through a handful of kinds and the given amount of names
+func generateInput(b *testing.B, documents, names int) string {
+	b.Helper()
+
+	fp := filepath.Join(b.TempDir(), "input.yaml")
+	f, err := os.Create(fp)
+	require.NoError(b, err)
+
+	w := bufio.NewWriter(f)
+	kinds := []string{"ConfigMap", "Secret", "Service", "Deployment", "Job"}
+	for i := 0; i < documents; i++ {
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+
+		fmt.Fprintf(w, "apiVersion: v1\nkind: %s\nmetadata:\n  name: app-%d\n  labels:\n    index: %q\ndata:\n  value: %q\n", kinds[i%len(kinds)], i%names, fmt.Sprint(i), strings.Repeat("x", 64))
+	}
+
+	require.NoError(b, w.Flush())
+	require.NoError(b, f.Close())
+
+	return fp
+}
+
+// peakHeap samples the heap in use until stop is called, and returns the
+// highest value seen
+func peakHeap() (stop func() uint64) {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	done := make(chan struct{})
+	result := make(chan uint64)
+
+	go func() {
+		var peak uint64
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			metrics.Read(sample)
+			if v := sample[0].Value.Uint64(); v > peak {
+				peak = v
+			}
+
+			select {
+			case <-done:
+				result <- peak
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return func() uint64 {
+		close(done)
+		return <-result
+	}
+}
+
+// BenchmarkExecute_stream compares reading the entire input first with
+// streaming it. The "peak-heap-MiB" metric grows with the amount of
+// documents when buffering, but stays flat when streaming.
+func BenchmarkExecute_stream(b *testing.B) {
+	for _, documents := range []int{1000, 10000, 100000} {
+		input := generateInput(b, documents, 20)
+
+		for _, stream := range []bool{false, true} {
+			mode := "buffered"
+			if stream {
+				mode = "stream"
+			}
+
+			b.Run(fmt.Sprintf("documents=%d/%s", documents, mode), func(b *testing.B) {
+				var peak uint64
+
+				for i := 0; i < b.N; i++ {
+					runtime.GC()
+					stop := peakHeap()
+
+					s, err := New(Options{
+						InputFile:      input,
+						GoTemplate:     "{{.kind | lower}}-{{.metadata.name}}.yaml",
+						OutputToStdout: true,
+						Stream:         stream,
+						Stdout:         io.Discard,
+						Stderr:         io.Discard,
+					})
+					require.NoError(b, err)
+					require.NoError(b, s.Execute())
+
+					if v := stop(); v > peak {
+						peak = v
+					}
+				}
+
+				b.ReportMetric(float64(peak)/(1<<20), "peak-heap-MiB")
+			})
+		}
+	}
+}
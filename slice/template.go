@@ -3,6 +3,7 @@ package slice
 import (
 	"errors"
 	"fmt"
+	"path"
 	"strings"
 	"text/template"
 
@@ -20,6 +21,28 @@ func (s *Split) compileTemplate() error {
 	return nil
 }
 
+// templateData returns the data used to render the file name of a manifest:
+// the manifest itself, plus the "__source" key with the input file and line
+// where the manifest starts, and the path of the input relative to the
+// folder, glob or archive it was found in, split in its folder and base
+// name. The manifest is not modified.
+func (s *Split) templateData(manifest map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(manifest)+1)
+	for k, v := range manifest {
+		data[k] = v
+	}
+
+	data["__source"] = map[string]interface{}{
+		"file": s.source,
+		"line": s.line,
+		"path": s.path,
+		"dir":  path.Dir(s.path),
+		"base": path.Base(s.path),
+	}
+
+	return data
+}
+
 func improveExecError(err error) error {
 	// Before you start screaming because I'm handling an error using strings,
 	// consider that there's a longstanding open TODO to improve template.ExecError
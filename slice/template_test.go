@@ -1,7 +1,12 @@
 package slice
 
 import (
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestTemplate_compileTemplate(t *testing.T) {
@@ -40,3 +45,77 @@ func TestTemplate_compileTemplate(t *testing.T) {
 		})
 	}
 }
+
+func TestTemplate_sourcePath(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"repo/namespace.yaml":         "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: prod\n",
+		"repo/apps/web/all.yaml":      "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n",
+		"repo/apps/db/deployment.yml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: db\n",
+	}
+
+	for name, contents := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644))
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		template string
+		want     []string
+	}{
+		{
+			name:     "mirror the input folder",
+			opts:     Options{InputFolder: filepath.Join(dir, "repo"), Recurse: true},
+			template: "{{.__source.dir}}/{{.kind | lower}}-{{.metadata.name}}.yaml",
+			want: []string{
+				"apps/db/deployment-db.yaml",
+				"apps/web/service-web.yaml",
+				"apps/web/deployment-web.yaml",
+				"namespace-prod.yaml",
+			},
+		},
+		{
+			name:     "relative to the glob pattern",
+			opts:     Options{InputFiles: []string{filepath.Join(dir, "repo", "apps", "**", "*.y*ml")}},
+			template: "{{.__source.path}}",
+			want: []string{
+				"db/deployment.yml",
+				"web/all.yaml",
+			},
+		},
+		{
+			name:     "single file",
+			opts:     Options{InputFiles: []string{filepath.Join(dir, "repo", "apps", "web", "all.yaml")}},
+			template: "{{.__source.dir}}/{{.__source.base}}-{{.__source.line}}.yaml",
+			want: []string{
+				"all.yaml-1.yaml",
+				"all.yaml-6.yaml",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.opts.GoTemplate = tt.template
+			tt.opts.OutputToStdout = true
+			tt.opts.Stdout = io.Discard
+			tt.opts.Stderr = io.Discard
+
+			s, err := New(tt.opts)
+			require.NoError(t, err)
+			require.NoError(t, s.Execute())
+
+			var got []string
+			for _, v := range s.filesFound {
+				got = append(got, filepath.ToSlash(v.filename))
+			}
+
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
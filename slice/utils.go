@@ -19,12 +19,49 @@ func inarray[T comparable](needle T, haystack []T) bool {
 	return false
 }
 
+// input is the YAML data read from a single source, like a file or stdin
+type input struct {
+	name    string // the path of the file the data was read from, or "stdin", followed by the path inside the archive for archive members
+	path    string // slash-separated path relative to the folder, glob or archive the input was found in
+	archive string // the path of the archive the input was read from, if any
+	data    []byte
+
+	// When streaming, the data isn't loaded ahead of time: it's read with
+	// open while it's scanned, and size is the amount of data known to be
+	// available
+	open func() (io.ReadCloser, error)
+	size int64
+}
+
+// id identifies the input regardless of how its location was provided: the
+// absolute path of the file it was read from, followed by the path inside
+// the archive for archive members. It's empty for inputs read from stdin.
+func (in input) id() string {
+	file := in.name
+	if in.archive != "" {
+		file = in.archive
+	}
+
+	if file == "stdin" {
+		return ""
+	}
+
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+
+	if in.archive != "" {
+		return file + ":" + in.path
+	}
+
+	return file
+}
+
 // loadfolder reads the folder contents recursively for `.yaml` and `.yml` files
-// and returns a buffer with the contents of all files found; returns the buffer
-// with all the files separated by `---` and the number of files found
-func loadfolder(extensions []string, folderPath string, recurse bool) (*bytes.Buffer, int, error) {
-	var buffer bytes.Buffer
-	var count int
+// and returns the contents of each one of the files found, in lexical order.
+// When streaming, the files are opened as they're scanned instead.
+func loadfolder(extensions []string, folderPath string, recurse, stream bool) ([]input, error) {
+	var inputs []input
 
 	err := filepath.Walk(folderPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -40,31 +77,37 @@ func loadfolder(extensions []string, folderPath string, recurse bool) (*bytes.Bu
 
 		ext := strings.ToLower(filepath.Ext(path))
 		if inarray(ext, extensions) {
-			count++
-
-			data, err := os.ReadFile(path)
+			rel, err := filepath.Rel(folderPath, path)
 			if err != nil {
 				return err
 			}
 
-			if buffer.Len() > 0 {
-				buffer.WriteString("\n---\n")
+			in := input{name: path, path: filepath.ToSlash(rel)}
+
+			if stream {
+				in.open, in.size = openInput(path), info.Size()
+				inputs = append(inputs, in)
+				return nil
+			}
+
+			if in.data, err = os.ReadFile(path); err != nil {
+				return err
 			}
 
-			buffer.Write(data)
+			inputs = append(inputs, in)
 		}
 
 		return nil
 	})
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	if buffer.Len() == 0 {
-		return nil, 0, fmt.Errorf("no files found in %q with extensions: %s", folderPath, strings.Join(extensions, ", "))
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no files found in %q with extensions: %s", folderPath, strings.Join(extensions, ", "))
 	}
 
-	return &buffer, count, nil
+	return inputs, nil
 }
 
 func loadfile(fp string) (*bytes.Buffer, error) {
@@ -99,6 +142,56 @@ func openFile(fp string) (*os.File, error) {
 	return f, nil
 }
 
+// resolvesWithin checks if the path, once any symlinks in it are resolved,
+// is still located inside the root directory. Elements of the path that
+// don't exist yet are assumed to be regular files or folders.
+func resolvesWithin(root, path string) (bool, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		// If the root doesn't exist yet, nothing inside it can be a symlink
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+
+		return false, err
+	}
+
+	// Find the longest portion of the path that exists on disk
+	existing, missing := filepath.Clean(path), ""
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return false, err
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+
+		missing = filepath.Join(filepath.Base(existing), missing)
+		existing = parent
+	}
+
+	realPath, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		// A dangling symlink can't be proven to be inside the root
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	rel, err := filepath.Rel(realRoot, filepath.Join(realPath, missing))
+	if err != nil {
+		return false, nil
+	}
+
+	return filepath.IsLocal(rel), nil
+}
+
 func deleteFolderContents(location string) error {
 	f, err := os.Open(location)
 	if err != nil {
@@ -1,10 +1,9 @@
 package slice
 
 import (
-	"bytes"
 	"fmt"
-	"path/filepath"
 	"regexp"
+	"strings"
 )
 
 var (
@@ -15,64 +14,147 @@ var (
 func (s *Split) init() error {
 	s.log.Printf("Initializing with settings: %#v", s.opts)
 
-	if s.opts.InputFile != "" && s.opts.InputFolder != "" {
-		return fmt.Errorf("cannot specify both input file and input folder")
-	}
+	hasFiles := s.opts.InputFile != "" || len(s.opts.InputFiles) > 0 || s.opts.InputFolder != ""
 
-	if s.opts.InputFile == "" && s.opts.InputFolder == "" {
+	if s.opts.Input == nil && !hasFiles {
 		return fmt.Errorf("input file or input folder is required")
 	}
 
-	var buf *bytes.Buffer
+	if s.opts.Input != nil && hasFiles {
+		return fmt.Errorf("cannot specify both an input reader and an input file or folder")
+	}
 
-	if s.opts.InputFile != "" {
-		s.log.Printf("Loading file %s", s.opts.InputFile)
-		var err error
-		buf, err = loadfile(s.opts.InputFile)
-		if err != nil {
+	if err := s.loadInputs(); err != nil {
+		return err
+	}
+
+	var size int64
+	for _, in := range s.inputs {
+		size += int64(len(in.data)) + in.size
+	}
+
+	if size == 0 {
+		return fmt.Errorf("no data found in input file or folder")
+	}
+
+	// When only listing documents nothing is written, so there's no
+	// output to validate
+	if !s.listing {
+		if err := s.validateOutput(); err != nil {
 			return err
 		}
 	}
 
-	if s.opts.InputFolder != "" {
-		exts := extensions
-		s.opts.InputFolder = filepath.Clean(s.opts.InputFolder)
+	if s.opts.OnConflict == "" {
+		s.opts.OnConflict = ConflictAppend
+	}
 
-		if len(s.opts.InputFolderExt) > 0 {
-			exts = s.opts.InputFolderExt
+	if !inarray(s.opts.OnConflict, conflictStrategies) {
+		return fmt.Errorf("invalid conflict strategy %q: must be one of: %s", s.opts.OnConflict, strings.Join(conflictStrategies, ", "))
+	}
+
+	if s.opts.OutputFormat != "" {
+		if !inarray(s.opts.OutputFormat, outputFormats) {
+			return fmt.Errorf("invalid output format %q: must be one of: %s", s.opts.OutputFormat, strings.Join(outputFormats, ", "))
 		}
 
-		s.log.Printf("Loading folder %q", s.opts.InputFolder)
-		var err error
-		var count int
-		buf, count, err = loadfolder(exts, s.opts.InputFolder, s.opts.Recurse)
-		if err != nil {
-			return err
+		if s.opts.Join {
+			return fmt.Errorf("cannot specify an output format when joining resources")
+		}
+
+		if s.opts.OutputFormat == OutputFormatJSON && s.opts.IncludeTripleDash {
+			return fmt.Errorf("cannot include the triple dash separator when the output format is %q", OutputFormatJSON)
+		}
+
+		// Use the JSON extension when using the default file name
+		if s.opts.OutputFormat == OutputFormatJSON && s.opts.GoTemplate == DefaultTemplateName {
+			s.opts.GoTemplate = strings.TrimSuffix(DefaultTemplateName, ".yaml") + ".json"
 		}
-		s.log.Printf("Found %d files in folder %q", count, s.opts.InputFolder)
 	}
 
-	if buf == nil || buf.Len() == 0 {
-		return fmt.Errorf("no data found in input file or folder")
+	if s.opts.Explain && s.opts.Quiet {
+		return fmt.Errorf("cannot specify both explain and quiet modes")
+	}
+
+	if err := s.compileTemplate(); err != nil {
+		return err
 	}
 
-	s.data = buf
+	return s.validateFilters()
+}
+
+// validateOutput checks the options describing where and how the files
+// are written
+func (s *Split) validateOutput() error {
+	switch {
+	case s.opts.Join:
+		if s.opts.OutputDirectory != "" || s.opts.OutputToStdout || s.opts.OutputArchive != "" || s.opts.Sink != nil {
+			return fmt.Errorf("cannot specify an output directory, archive, sink or output to stdout when joining resources: use the output file instead")
+		}
+
+	case s.opts.Sink != nil:
+		if s.opts.OutputDirectory != "" || s.opts.OutputToStdout || s.opts.OutputArchive != "" || s.opts.DryRun {
+			return fmt.Errorf("cannot specify both a sink and an output directory, archive, output to stdout or dry-run")
+		}
+
+		if s.opts.PruneOutputDir {
+			return fmt.Errorf("cannot prune the output directory when writing to a sink")
+		}
+
+	case s.opts.OutputArchive != "":
+		if s.opts.OutputDirectory != "" || s.opts.OutputToStdout {
+			return fmt.Errorf("cannot specify both output archive and output directory or output to stdout")
+		}
 
-	if s.opts.OutputToStdout {
+		if s.opts.PruneOutputDir {
+			return fmt.Errorf("cannot prune the output directory when writing to an output archive")
+		}
+
+		if _, err := archiveFormat(s.opts.OutputArchive); err != nil {
+			return err
+		}
+
+	case s.opts.OutputToStdout:
 		if s.opts.OutputDirectory != "" {
 			return fmt.Errorf("cannot specify both output to stdout and output to file: output directory flag is set")
 		}
-	} else {
+
+	default:
 		if s.opts.OutputDirectory == "" {
 			return fmt.Errorf("output directory flag is empty or not set")
 		}
 	}
 
-	if err := s.compileTemplate(); err != nil {
-		return err
+	if s.opts.Kustomize && (s.opts.OutputToStdout || s.opts.Join) {
+		return fmt.Errorf("cannot generate kustomization files when output is not written to a directory")
 	}
 
-	return s.validateFilters()
+	if s.opts.Report == "-" && s.opts.OutputToStdout {
+		return fmt.Errorf("cannot specify both output to stdout and report to stdout")
+	}
+
+	if s.opts.Stream {
+		switch {
+		case s.opts.SortByKind:
+			return fmt.Errorf("cannot sort resources by kind when streaming: sorting requires reading all the resources first")
+
+		case s.opts.Join:
+			return fmt.Errorf("cannot stream resources when joining them")
+
+		case s.opts.OutputArchive != "":
+			return fmt.Errorf("cannot stream resources to an output archive")
+
+		case s.opts.OutputToStdout && s.opts.OnConflict == ConflictOverwrite:
+			return fmt.Errorf("cannot overwrite resources already printed to stdout when streaming: use a different conflict strategy")
+		}
+
+		// Resources rendering the same file name are appended to it
+		if _, ok := s.opts.Sink.(AppendSink); s.opts.Sink != nil && !ok && (s.opts.OnConflict == "" || s.opts.OnConflict == ConflictAppend) {
+			return fmt.Errorf("cannot stream resources to a sink that can't append to files: use a different conflict strategy")
+		}
+	}
+
+	return nil
 }
 
 func (s *Split) validateFilters() error {
@@ -92,22 +174,6 @@ func (s *Split) validateFilters() error {
 		return fmt.Errorf("cannot specify both excluded names and allow empty names")
 	}
 
-	if len(s.opts.IncludedKinds) > 0 && len(s.opts.ExcludedKinds) > 0 {
-		return fmt.Errorf("cannot specify both included and excluded kinds")
-	}
-
-	if len(s.opts.IncludedNames) > 0 && len(s.opts.ExcludedNames) > 0 {
-		return fmt.Errorf("cannot specify both included and excluded names")
-	}
-
-	if len(s.opts.Included) > 0 && len(s.opts.Excluded) > 0 {
-		return fmt.Errorf("cannot specify both included and excluded")
-	}
-
-	if len(s.opts.ExcludedGroups) > 0 && len(s.opts.IncludedGroups) > 0 {
-		return fmt.Errorf("cannot specify both included and excluded groups")
-	}
-
 	// Merge all filters into excluded and included.
 	for _, v := range s.opts.IncludedKinds {
 		s.opts.Included = append(s.opts.Included, fmt.Sprintf("%s/*", v))
@@ -138,5 +204,60 @@ func (s *Split) validateFilters() error {
 		}
 	}
 
+	// Compile included and excluded regular expressions. They're case
+	// insensitive, just like the glob patterns.
+	s.includedRegex, s.excludedRegex = nil, nil
+	for _, included := range s.opts.IncludedRegex {
+		re, err := regexp.Compile("(?i)" + included)
+		if err != nil {
+			return fmt.Errorf("invalid included regular expression %q: %w", included, err)
+		}
+
+		s.includedRegex = append(s.includedRegex, re)
+	}
+
+	for _, excluded := range s.opts.ExcludedRegex {
+		re, err := regexp.Compile("(?i)" + excluded)
+		if err != nil {
+			return fmt.Errorf("invalid excluded regular expression %q: %w", excluded, err)
+		}
+
+		s.excludedRegex = append(s.excludedRegex, re)
+	}
+
+	// Validate included and excluded annotations.
+	for _, included := range s.opts.IncludedAnnotations {
+		if key, _, _ := strings.Cut(included, "="); key == "" {
+			return fmt.Errorf("invalid included annotation %q should be <key> or <key>=<value>", included)
+		}
+	}
+
+	for _, excluded := range s.opts.ExcludedAnnotations {
+		if key, _, _ := strings.Cut(excluded, "="); key == "" {
+			return fmt.Errorf("invalid excluded annotation %q should be <key> or <key>=<value>", excluded)
+		}
+	}
+
+	// Parse the label selector, if any.
+	if selector := strings.TrimSpace(s.opts.LabelSelector); selector != "" {
+		parsed, err := parseLabelSelector(selector)
+		if err != nil {
+			return err
+		}
+
+		s.labelSelector = parsed
+	}
+
+	// Compile the where expressions, if any.
+	s.where = nil
+	for _, expr := range s.opts.Where {
+		compiled, err := parseWhereExpression(expr)
+		if err != nil {
+			return err
+		}
+
+		s.where = append(s.where, compiled)
+	}
+
 	return nil
 }
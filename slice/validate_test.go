@@ -43,11 +43,92 @@ func TestSplit_validateFilters(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name: "cannot specify included and excluded kinds",
+			name: "allow included and excluded kinds",
 			opts: Options{
 				IncludedKinds: []string{"foo"},
 				ExcludedKinds: []string{"bar"},
 			},
+		},
+		{
+			name: "allow included and excluded names",
+			opts: Options{
+				IncludedNames: []string{"foo"},
+				ExcludedNames: []string{"bar"},
+			},
+		},
+		{
+			name: "allow included and excluded kind/name",
+			opts: Options{
+				Included: []string{"foo/*"},
+				Excluded: []string{"*/bar"},
+			},
+		},
+		{
+			name: "allow included and excluded groups",
+			opts: Options{
+				IncludedGroups: []string{"apps"},
+				ExcludedGroups: []string{"batch"},
+			},
+		},
+		{
+			name: "allow included and excluded namespaces",
+			opts: Options{
+				IncludedNamespaces: []string{"foo"},
+				ExcludedNamespaces: []string{"bar"},
+			},
+		},
+		{
+			name: "allow included and excluded annotations",
+			opts: Options{
+				IncludedAnnotations: []string{"foo"},
+				ExcludedAnnotations: []string{"bar"},
+			},
+		},
+		{
+			name: "annotation without key",
+			opts: Options{
+				ExcludedAnnotations: []string{"=bar"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "annotation with key and value",
+			opts: Options{
+				ExcludedAnnotations: []string{"helm.sh/hook=*"},
+			},
+		},
+		{
+			name: "valid regular expressions",
+			opts: Options{
+				IncludedRegex: []string{`^deployment/(api|worker)-v[0-9]{1,3}$`},
+				ExcludedRegex: []string{`-canary$`},
+			},
+		},
+		{
+			name: "invalid included regular expression",
+			opts: Options{
+				IncludedRegex: []string{`^deployment/(api|worker`},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid excluded regular expression",
+			opts: Options{
+				ExcludedRegex: []string{`[a-`},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid where expressions",
+			opts: Options{
+				Where: []string{"spec.replicas > 1", `metadata.labels.team == "payments"`},
+			},
+		},
+		{
+			name: "invalid where expression",
+			opts: Options{
+				Where: []string{"spec.replicas > 1", "spec.replicas = 1"},
+			},
 			wantErr: true,
 		},
 	}
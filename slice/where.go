@@ -0,0 +1,620 @@
+package slice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// whereExpression is a compiled "--where" filter expression, evaluated against
+// the same manifest map the file name template receives. Expressions support
+// field paths (like "spec.replicas" or `metadata.labels["app.kubernetes.io/name"]`),
+// string, number, boolean and null literals, the comparison operators "==",
+// "!=", ">", ">=", "<" and "<=", the logical operators "&&", "||" and "!",
+// parenthesis, and the "has(path)" function to check if a field exists.
+type whereExpression struct {
+	raw  string
+	root whereNode
+}
+
+// parseWhereExpression compiles a "--where" expression, returning an error if
+// the expression is not valid
+func parseWhereExpression(expr string) (*whereExpression, error) {
+	tokens, err := lexWhere(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid where expression %q: %w", expr, err)
+	}
+
+	p := &whereParser{tokens: tokens}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid where expression %q: %w", expr, err)
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("invalid where expression %q: unexpected %s at position %d", expr, tok, tok.pos)
+	}
+
+	return &whereExpression{raw: expr, root: root}, nil
+}
+
+// matches evaluates the expression against the given manifest
+func (w *whereExpression) matches(manifest map[string]interface{}) bool {
+	return truthy(w.root.eval(manifest))
+}
+
+func (w *whereExpression) String() string {
+	return w.raw
+}
+
+type whereTokenKind int
+
+const (
+	tokenEOF whereTokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type whereToken struct {
+	kind  whereTokenKind
+	value string
+	pos   int
+}
+
+func (t whereToken) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return fmt.Sprintf("string %q", t.value)
+	default:
+		return fmt.Sprintf("%q", t.value)
+	}
+}
+
+// whereOperators is the list of operators and punctuation supported, with
+// the two-character operators first so they take precedence when lexing
+var whereOperators = []string{"==", "!=", ">=", "<=", "&&", "||", ">", "<", "!", "(", ")", "[", "]", "."}
+
+func lexWhere(expr string) ([]whereToken, error) {
+	var tokens []whereToken
+
+	for pos := 0; pos < len(expr); {
+		c := rune(expr[pos])
+
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+
+		case c == '"' || c == '\'':
+			end := pos + 1
+			for end < len(expr) && rune(expr[end]) != c {
+				if expr[end] == '\\' && c == '"' {
+					end++
+				}
+				end++
+			}
+
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string starting at position %d", pos)
+			}
+
+			value := expr[pos+1 : end]
+			if c == '"' {
+				unquoted, err := strconv.Unquote(expr[pos : end+1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid string at position %d: %w", pos, err)
+				}
+				value = unquoted
+			}
+
+			tokens = append(tokens, whereToken{kind: tokenString, value: value, pos: pos})
+			pos = end + 1
+
+		case unicode.IsDigit(c) || (c == '-' && pos+1 < len(expr) && unicode.IsDigit(rune(expr[pos+1]))):
+			end := scanDigits(expr, pos+1)
+
+			// A number right after a "." is a list index, like in "items.0.1",
+			// so it can't have a fraction or an exponent
+			if !afterDot(tokens) {
+				if end+1 < len(expr) && expr[end] == '.' && unicode.IsDigit(rune(expr[end+1])) {
+					end = scanDigits(expr, end+1)
+				}
+
+				if end < len(expr) && (expr[end] == 'e' || expr[end] == 'E') {
+					exp := end + 1
+					if exp < len(expr) && (expr[exp] == '+' || expr[exp] == '-') {
+						exp++
+					}
+
+					if exp < len(expr) && unicode.IsDigit(rune(expr[exp])) {
+						end = scanDigits(expr, exp)
+					}
+				}
+			}
+
+			tokens = append(tokens, whereToken{kind: tokenNumber, value: expr[pos:end], pos: pos})
+			pos = end
+
+		case unicode.IsLetter(c) || c == '_':
+			end := pos + 1
+			for end < len(expr) && isIdentChar(rune(expr[end])) {
+				end++
+			}
+
+			tokens = append(tokens, whereToken{kind: tokenIdent, value: expr[pos:end], pos: pos})
+			pos = end
+
+		default:
+			found := false
+			for _, op := range whereOperators {
+				if strings.HasPrefix(expr[pos:], op) {
+					tokens = append(tokens, whereToken{kind: tokenOperator, value: op, pos: pos})
+					pos += len(op)
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, pos)
+			}
+		}
+	}
+
+	return append(tokens, whereToken{kind: tokenEOF, pos: len(expr)}), nil
+}
+
+// scanDigits returns the position of the first non-digit character in the
+// expression, starting at the given position
+func scanDigits(expr string, pos int) int {
+	for pos < len(expr) && unicode.IsDigit(rune(expr[pos])) {
+		pos++
+	}
+	return pos
+}
+
+// afterDot reports whether the last token lexed is a "." operator
+func afterDot(tokens []whereToken) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+
+	last := tokens[len(tokens)-1]
+	return last.kind == tokenOperator && last.value == "."
+}
+
+func isIdentChar(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '-'
+}
+
+type whereParser struct {
+	tokens []whereToken
+	pos    int
+}
+
+func (p *whereParser) peek() whereToken {
+	return p.tokens[p.pos]
+}
+
+func (p *whereParser) next() whereToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *whereParser) isOperator(values ...string) bool {
+	tok := p.peek()
+	return tok.kind == tokenOperator && inarray(tok.value, values)
+}
+
+func (p *whereParser) expect(value string) error {
+	if tok := p.next(); tok.kind != tokenOperator || tok.value != value {
+		return fmt.Errorf("expecting %q but got %s at position %d", value, tok, tok.pos)
+	}
+	return nil
+}
+
+// parseOr handles: and ( "||" and )*
+func (p *whereParser) parseOr() (whereNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("||") {
+		p.next()
+
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &whereLogical{op: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseAnd handles: not ( "&&" not )*
+func (p *whereParser) parseAnd() (whereNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator("&&") {
+		p.next()
+
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &whereLogical{op: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+// parseNot handles: "!" not | comparison
+func (p *whereParser) parseNot() (whereNode, error) {
+	if p.isOperator("!") {
+		p.next()
+
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+
+		return &whereNot{node: node}, nil
+	}
+
+	return p.parseComparison()
+}
+
+// parseComparison handles: operand ( op operand )?
+func (p *whereParser) parseComparison() (whereNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.isOperator("==", "!=", ">", ">=", "<", "<=") {
+		op := p.next().value
+
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		return &whereComparison{op: op, left: left, right: right}, nil
+	}
+
+	return left, nil
+}
+
+// parseOperand handles literals, paths, "has(path)" and parenthesized expressions
+func (p *whereParser) parseOperand() (whereNode, error) {
+	tok := p.peek()
+
+	switch tok.kind {
+	case tokenString:
+		p.next()
+		return &whereLiteral{value: tok.value}, nil
+
+	case tokenNumber:
+		p.next()
+
+		n, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", tok.value, tok.pos)
+		}
+
+		return &whereLiteral{value: n}, nil
+
+	case tokenIdent:
+		switch tok.value {
+		case "true", "false":
+			p.next()
+			return &whereLiteral{value: tok.value == "true"}, nil
+
+		case "null":
+			p.next()
+			return &whereLiteral{value: nil}, nil
+
+		case "has":
+			if next := p.tokens[p.pos+1]; next.kind == tokenOperator && next.value == "(" {
+				p.next()
+				p.next()
+
+				path, err := p.parsePath()
+				if err != nil {
+					return nil, err
+				}
+
+				if err := p.expect(")"); err != nil {
+					return nil, err
+				}
+
+				return &whereHas{path: path}, nil
+			}
+		}
+
+		return p.parsePath()
+
+	case tokenOperator:
+		if tok.value == "(" {
+			p.next()
+
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+
+			return node, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos)
+}
+
+// parsePath handles: ident ( "." ident | "." number | "[" number "]" | "[" string "]" )*
+func (p *whereParser) parsePath() (*wherePath, error) {
+	tok := p.next()
+	if tok.kind != tokenIdent {
+		return nil, fmt.Errorf("expecting a field name but got %s at position %d", tok, tok.pos)
+	}
+
+	path := &wherePath{segments: []interface{}{tok.value}}
+
+	for {
+		switch {
+		case p.isOperator("."):
+			p.next()
+
+			seg := p.next()
+			switch seg.kind {
+			case tokenIdent:
+				path.segments = append(path.segments, seg.value)
+
+			case tokenNumber:
+				n, err := strconv.Atoi(seg.value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q at position %d", seg.value, seg.pos)
+				}
+				path.segments = append(path.segments, n)
+
+			default:
+				return nil, fmt.Errorf("expecting a field name after \".\" but got %s at position %d", seg, seg.pos)
+			}
+
+		case p.isOperator("["):
+			p.next()
+
+			seg := p.next()
+			switch seg.kind {
+			case tokenString:
+				path.segments = append(path.segments, seg.value)
+
+			case tokenNumber:
+				n, err := strconv.Atoi(seg.value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %q at position %d", seg.value, seg.pos)
+				}
+				path.segments = append(path.segments, n)
+
+			default:
+				return nil, fmt.Errorf("expecting a string or an index inside \"[]\" but got %s at position %d", seg, seg.pos)
+			}
+
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+
+		default:
+			return path, nil
+		}
+	}
+}
+
+type whereNode interface {
+	eval(manifest map[string]interface{}) interface{}
+}
+
+type whereLiteral struct {
+	value interface{}
+}
+
+func (n *whereLiteral) eval(map[string]interface{}) interface{} {
+	return n.value
+}
+
+type wherePath struct {
+	segments []interface{}
+}
+
+func (n *wherePath) eval(manifest map[string]interface{}) interface{} {
+	value, _ := n.lookup(manifest)
+	return value
+}
+
+// lookup walks the manifest following the path segments, returning the value
+// found and whether the full path exists
+func (n *wherePath) lookup(manifest map[string]interface{}) (interface{}, bool) {
+	var current interface{} = manifest
+
+	for _, segment := range n.segments {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			key, ok := segment.(string)
+			if !ok {
+				key = strconv.Itoa(segment.(int))
+			}
+
+			value, found := node[key]
+			if !found {
+				return nil, false
+			}
+			current = value
+
+		case []interface{}:
+			index, ok := segment.(int)
+			if !ok || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+type whereHas struct {
+	path *wherePath
+}
+
+func (n *whereHas) eval(manifest map[string]interface{}) interface{} {
+	_, found := n.path.lookup(manifest)
+	return found
+}
+
+type whereNot struct {
+	node whereNode
+}
+
+func (n *whereNot) eval(manifest map[string]interface{}) interface{} {
+	return !truthy(n.node.eval(manifest))
+}
+
+type whereLogical struct {
+	op          string
+	left, right whereNode
+}
+
+func (n *whereLogical) eval(manifest map[string]interface{}) interface{} {
+	left := truthy(n.left.eval(manifest))
+
+	if n.op == "&&" {
+		return left && truthy(n.right.eval(manifest))
+	}
+
+	return left || truthy(n.right.eval(manifest))
+}
+
+type whereComparison struct {
+	op          string
+	left, right whereNode
+}
+
+func (n *whereComparison) eval(manifest map[string]interface{}) interface{} {
+	left, right := normalizeWhereValue(n.left.eval(manifest)), normalizeWhereValue(n.right.eval(manifest))
+
+	switch n.op {
+	case "==":
+		return whereEquals(left, right)
+	case "!=":
+		return !whereEquals(left, right)
+	}
+
+	// Ordering comparisons are only possible between numbers or between
+	// strings; anything else, including missing fields, doesn't match
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		}
+
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+
+		cmp = strings.Compare(l, r)
+
+	default:
+		return false
+	}
+
+	switch n.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+
+	return false
+}
+
+// normalizeWhereValue converts all the numeric types the YAML parser
+// might produce into float64 so they can be compared with literals
+func normalizeWhereValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	}
+
+	return v
+}
+
+// whereEquals compares two scalar values, with maps and lists never
+// being equal to anything
+func whereEquals(left, right interface{}) bool {
+	switch left.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+
+	switch right.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+
+	return left == right
+}
+
+// truthy converts the result of an expression into a boolean: booleans are
+// used as-is, missing or null fields are false, and any other value is true
+func truthy(v interface{}) bool {
+	switch b := v.(type) {
+	case bool:
+		return b
+	case nil:
+		return false
+	}
+
+	return true
+}
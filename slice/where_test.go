@@ -0,0 +1,137 @@
+package slice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func Test_parseWhereExpression(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{name: "comparison", expr: "spec.replicas > 1"},
+		{name: "string equality", expr: `metadata.labels.team == "payments"`},
+		{name: "single quoted string", expr: `metadata.labels.team == 'payments'`},
+		{name: "has function", expr: "has(spec.template.spec.hostNetwork)"},
+		{name: "bracket access", expr: `metadata.labels["app.kubernetes.io/name"] == "web"`},
+		{name: "index access", expr: `spec.containers[0].name == "nginx" && spec.containers.1.name == "sidecar"`},
+		{name: "logical operators", expr: `!(kind == "Secret") && (spec.replicas >= 2 || has(spec.strategy))`},
+		{name: "negative numbers and floats", expr: "spec.value > -1.5"},
+		{name: "exponents", expr: "spec.value < 1e5 && spec.value > 2.5E-3"},
+		{name: "nested dotted index", expr: "spec.items.0.1 == 2"},
+		{name: "bare field", expr: "spec.template.spec.hostNetwork"},
+		{name: "null literal", expr: "metadata.namespace != null"},
+		{name: "field with dashes", expr: "data.my-key == 'foo'"},
+		{name: "empty", expr: "", wantErr: true},
+		{name: "single equals", expr: "spec.replicas = 1", wantErr: true},
+		{name: "missing right operand", expr: "spec.replicas >", wantErr: true},
+		{name: "unterminated string", expr: `kind == "Deployment`, wantErr: true},
+		{name: "unbalanced parenthesis", expr: `(kind == "Deployment"`, wantErr: true},
+		{name: "trailing tokens", expr: `kind == "Deployment" "Pod"`, wantErr: true},
+		{name: "has without path", expr: `has("kind")`, wantErr: true},
+		{name: "has without closing parenthesis", expr: `has(kind`, wantErr: true},
+		{name: "invalid bracket", expr: `metadata.labels[app]`, wantErr: true},
+		{name: "dangling dot", expr: `metadata.`, wantErr: true},
+		{name: "unknown character", expr: `kind ~= "Pod"`, wantErr: true},
+		{name: "chained comparison", expr: `1 < spec.replicas < 3`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parseWhereExpression(tt.expr)
+			requireErrorIf(t, tt.wantErr, err)
+		})
+	}
+}
+
+func Test_whereExpression_matches(t *testing.T) {
+	const manifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: payments-api
+  labels:
+    team: payments
+    app.kubernetes.io/name: api
+spec:
+  replicas: 3
+  paused: false
+  ratio: 0.5
+  strategy: null
+  matrix: [[1, 2], [3, 4]]
+  template:
+    spec:
+      hostNetwork: true
+      containers:
+      - name: api
+        image: payments/api:1.0
+      - name: sidecar
+`
+
+	var parsed map[string]interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(manifest), &parsed))
+
+	tests := []struct {
+		name string
+		expr string
+		want bool
+	}{
+		{name: "number greater than", expr: "spec.replicas > 1", want: true},
+		{name: "number greater than, not matching", expr: "spec.replicas > 3", want: false},
+		{name: "number greater or equal", expr: "spec.replicas >= 3", want: true},
+		{name: "number lower than", expr: "spec.replicas < 3", want: false},
+		{name: "number lower or equal", expr: "spec.replicas <= 3", want: true},
+		{name: "number equality", expr: "spec.replicas == 3", want: true},
+		{name: "float comparison", expr: "spec.ratio < 1", want: true},
+		{name: "string equality", expr: `metadata.labels.team == "payments"`, want: true},
+		{name: "string equality is case sensitive", expr: `metadata.labels.team == "Payments"`, want: false},
+		{name: "string inequality", expr: `kind != "Deployment"`, want: false},
+		{name: "string ordering", expr: `metadata.name > "a"`, want: true},
+		{name: "bracket access", expr: `metadata.labels["app.kubernetes.io/name"] == "api"`, want: true},
+		{name: "index access", expr: `spec.template.spec.containers[1].name == "sidecar"`, want: true},
+		{name: "dotted index access", expr: `spec.template.spec.containers.0.name == "api"`, want: true},
+		{name: "nested dotted index access", expr: `spec.matrix.1.0 == 3`, want: true},
+		{name: "nested bracket index access", expr: `spec.matrix[0][1] == 2`, want: true},
+		{name: "exponent", expr: "spec.replicas < 1e5", want: true},
+		{name: "negative exponent", expr: "spec.ratio == 5e-1", want: true},
+		{name: "exponent with fraction", expr: "spec.replicas == 0.3E+1", want: true},
+		{name: "index out of range", expr: `spec.template.spec.containers[5].name == "api"`, want: false},
+		{name: "has existing field", expr: "has(spec.template.spec.hostNetwork)", want: true},
+		{name: "has missing field", expr: "has(spec.template.spec.dnsPolicy)", want: false},
+		{name: "has null field", expr: "has(spec.strategy)", want: true},
+		{name: "null comparison", expr: "spec.strategy == null", want: true},
+		{name: "missing field is null", expr: "metadata.namespace == null", want: true},
+		{name: "bare true field", expr: "spec.template.spec.hostNetwork", want: true},
+		{name: "bare false field", expr: "spec.paused", want: false},
+		{name: "bare missing field", expr: "spec.missing", want: false},
+		{name: "bare non-boolean field", expr: "metadata.name", want: true},
+		{name: "boolean equality", expr: "spec.paused == false", want: true},
+		{name: "negation", expr: "!spec.paused", want: true},
+		{name: "and", expr: `kind == "Deployment" && spec.replicas > 1`, want: true},
+		{name: "and, one side false", expr: `kind == "Deployment" && spec.replicas > 5`, want: false},
+		{name: "or", expr: `kind == "Secret" || spec.replicas > 1`, want: true},
+		{name: "precedence", expr: `kind == "Secret" && spec.replicas > 1 || has(spec.template)`, want: true},
+		{name: "parenthesis", expr: `kind == "Secret" && (spec.replicas > 1 || has(spec.template))`, want: false},
+		{name: "type mismatch on ordering", expr: `metadata.name > 1`, want: false},
+		{name: "type mismatch on equality", expr: `spec.replicas == "3"`, want: false},
+		{name: "ordering on missing field", expr: `spec.missing > 1`, want: false},
+		{name: "map never equal", expr: `metadata.labels == null`, want: false},
+		{name: "path through scalar", expr: `has(kind.foo)`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expr, err := parseWhereExpression(tt.expr)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, expr.matches(parsed))
+		})
+	}
+}